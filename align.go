@@ -0,0 +1,166 @@
+package csv2mdtable
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Set the alignment of each column. Precedence, from highest to lowest: ColumnAlign (by name),
+// ColumnIndexAlign (by index), the alignment detected from the values if AutoAlign is set and finally Align.
+// The detector may be nil when the values of the columns are not known yet.
+func alignColumns(columns []Column, columnIndices []int, cfg Config, detector *alignmentDetector) {
+	for i := range columns {
+		columns[i].Align = cfg.Align
+
+		if detector != nil {
+			if detected, ok := detector.detectedAlign(i); ok {
+				columns[i].Align = detected
+			}
+		}
+
+		if align, ok := cfg.ColumnIndexAlign[columnIndices[i]]; ok {
+			columns[i].Align = align
+		}
+
+		if align, ok := cfg.ColumnAlign[columns[i].headerName()]; ok {
+			columns[i].Align = align
+		}
+	}
+}
+
+// Collects what kind of values each column holds, to pick a fitting alignment for the column
+type alignmentDetector struct {
+	// does the column hold at least one non-empty value?
+	hasValues []bool
+
+	// are all non-empty values of the column numbers, amounts of money or percentages?
+	numeric []bool
+
+	// are all non-empty values of the column boolean-like?
+	boolean []bool
+}
+
+func newAlignmentDetector(colCount int) *alignmentDetector {
+	detector := &alignmentDetector{
+		hasValues: make([]bool, colCount),
+		numeric:   make([]bool, colCount),
+		boolean:   make([]bool, colCount),
+	}
+
+	for i := range colCount {
+		detector.numeric[i] = true
+		detector.boolean[i] = true
+	}
+
+	return detector
+}
+
+// Take the values of a data row into account
+func (d *alignmentDetector) observe(row []string) {
+	for i, val := range row {
+		val = strings.TrimSpace(val)
+
+		if val == "" {
+			continue
+		}
+
+		d.hasValues[i] = true
+		d.numeric[i] = d.numeric[i] && isNumericValue(val)
+		d.boolean[i] = d.boolean[i] && isBooleanValue(val)
+	}
+}
+
+// Get the alignment fitting the values of the column. Numeric columns are aligned right,
+// boolean-like columns are centered and text is aligned left. Columns without any value are not detected.
+func (d *alignmentDetector) detectedAlign(colIdx int) (Align, bool) {
+	switch {
+	case !d.hasValues[colIdx]:
+		return Center, false
+	case d.numeric[colIdx]:
+		return Right, true
+	case d.boolean[colIdx]:
+		return Center, true
+	default:
+		return Left, true
+	}
+}
+
+// Check whether the value is a number, an amount of money or a percentage,
+// e.g. 42, -3.14, 1,234.50, $12, 12 €, (1,000.00) or 15%
+func isNumericValue(val string) bool {
+	_, ok := parseNumericValue(val)
+	return ok
+}
+
+// Parse a number, an amount of money or a percentage, see isNumericValue.
+// Percentages are parsed as the number in front of the percent sign.
+func parseNumericValue(val string) (float64, bool) {
+	negative := false
+
+	// accounting notation for negative amounts
+	if strings.HasPrefix(val, "(") && strings.HasSuffix(val, ")") {
+		val = val[1 : len(val)-1]
+		negative = true
+	}
+
+	val, negative = trimSign(val, negative)
+	val = strings.TrimSuffix(val, "%")
+	val = trimCurrencySymbol(val)
+	val, negative = trimSign(val, negative)
+	val = strings.ReplaceAll(val, ",", "")
+
+	// strconv also accepts values like "Inf", "NaN" or "0x1p-2", which should be treated as text
+	if val == "" || strings.ContainsFunc(val, isNotNumberRune) {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if negative {
+		return -number, true
+	}
+
+	return number, true
+}
+
+// Remove a leading sign from the value. A minus sign flips negative.
+func trimSign(val string, negative bool) (string, bool) {
+	if rest, ok := strings.CutPrefix(val, "-"); ok {
+		return rest, !negative
+	}
+
+	return strings.TrimPrefix(val, "+"), negative
+}
+
+// Check whether the rune cannot be part of a decimal number
+func isNotNumberRune(r rune) bool {
+	return !unicode.IsDigit(r) && r != '.' && r != 'e' && r != 'E' && r != '-' && r != '+'
+}
+
+// Remove a leading or trailing currency symbol, along with the space separating it from the amount
+func trimCurrencySymbol(val string) string {
+	if r, size := utf8.DecodeRuneInString(val); unicode.Is(unicode.Sc, r) {
+		return strings.TrimSpace(val[size:])
+	}
+
+	if r, size := utf8.DecodeLastRuneInString(val); unicode.Is(unicode.Sc, r) {
+		return strings.TrimSpace(val[:len(val)-size])
+	}
+
+	return val
+}
+
+// Check whether the value looks like a boolean, e.g. true, No or off
+func isBooleanValue(val string) bool {
+	switch strings.ToLower(val) {
+	case "true", "false", "yes", "no", "y", "n", "on", "off", "t", "f":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+)
+
+// Renders an AsciiDoc table (|===), with the alignment of every column set in its cols attribute.
+// The caption of the table becomes the block title.
+type AsciiDocRenderer struct{}
+
+func (r AsciiDocRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r AsciiDocRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	if table.Caption != "" {
+		// a line break would end the block title
+		head.WriteString("." + strings.Join(strings.Fields(table.Caption), " ") + "\n")
+	}
+
+	specifiers := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		specifiers[i] = asciiDocAlignSpecifier(column.Align)
+	}
+
+	head.WriteString(`[cols="` + strings.Join(specifiers, ",") + `",options="header"]` + "\n")
+	head.WriteString("|===\n")
+
+	if _, err := io.WriteString(w, head.String()); err != nil {
+		return err
+	}
+
+	return r.writeRow(w, table, table.Header(), maxLenOfCol, 0)
+}
+
+func (r AsciiDocRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	cells := make([]string, len(row))
+	for i, val := range row {
+		cells[i] = "|" + escapeAsciiDocCell(val)
+	}
+
+	_, err := io.WriteString(w, strings.Join(cells, " ")+"\n")
+	return err
+}
+
+func (r AsciiDocRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	_, err := io.WriteString(w, "|===\n")
+	return err
+}
+
+// Get the horizontal alignment specifier of a column: < (left), ^ (center) or > (right)
+func asciiDocAlignSpecifier(align Align) string {
+	switch align {
+	case Left:
+		return "<"
+	case Right:
+		return ">"
+	}
+
+	return "^"
+}
+
+// Escape the cell separators of a value. Line breaks in the value become hard line breaks.
+func escapeAsciiDocCell(val string) string {
+	val = strings.ReplaceAll(val, "|", `\|`)
+	val = strings.ReplaceAll(val, "\r\n", "\n")
+	return strings.ReplaceAll(val, "\n", " +\n")
+}
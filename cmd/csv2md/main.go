@@ -0,0 +1,655 @@
+// Command csv2md converts CSV files into Markdown tables.
+//
+// Usage:
+//
+//	csv2md [flags] [file or glob ...]
+//	csv2md -update [flags] markdown-file-or-glob ...
+//	csv2md -check [flags] markdown-file-or-glob ...
+//	csv2md -reformat [flags] [markdown-file-or-glob ...]
+//
+// Without any file, or with "-", the CSV is read from the standard input.
+// With -update, the tables between csv2md marker comments of the given Markdown files are regenerated in place.
+// With -check, the files are left untouched and a unified diff is printed for every table that is out of date.
+// With -reformat, every pipe table of the given Markdown files is re-rendered in place, or from the standard
+// input to the standard output if no file is given.
+// Every option of csv2mdtable.Config is available as a flag, run csv2md -help to list them.
+//
+// Exit codes: 0 on success, 1 if a CSV could not be parsed or converted,
+// 2 if the flags or the configuration are invalid, 3 if an input or output file could not be accessed
+// and 4 if -check found tables that are out of date.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	csv2mdtable "github.com/phamduylong/csv-to-md"
+)
+
+const (
+	exitOK          = 0
+	exitParseError  = 1
+	exitConfigError = 2
+	exitIOError     = 3
+	exitStale       = 4
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Run the command with the given arguments and return its exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("csv2md", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: csv2md [flags] [file or glob ...]")
+		fmt.Fprintln(stderr, "       csv2md -update [flags] markdown-file-or-glob ...")
+		fmt.Fprintln(stderr, "       csv2md -check [flags] markdown-file-or-glob ...")
+		fmt.Fprintln(stderr, "       csv2md -reformat [flags] [markdown-file-or-glob ...]")
+		fmt.Fprintln(stderr, "Converts CSV files (or the standard input) into Markdown tables.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	opts := newOptions(flags)
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+
+	// terminal tables fit the terminal they are printed to, unless a width is given
+	if opts.maxWidth == 0 && opts.output == "" {
+		if file, ok := stdout.(*os.File); ok {
+			opts.maxWidth = csv2mdtable.TerminalWidth(file)
+		}
+	}
+
+	cfg, err := opts.config()
+
+	if err == nil {
+		err = csv2mdtable.ValidateConfig(cfg)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md: invalid configuration:", err)
+		return exitConfigError
+	}
+
+	if countTrue(opts.update, opts.check, opts.reformat) > 1 {
+		fmt.Fprintln(stderr, "csv2md: only one of -update, -check and -reformat can be used")
+		return exitConfigError
+	}
+
+	if opts.update {
+		return updateDocuments(flags.Args(), cfg, stdout, stderr)
+	}
+
+	if opts.check {
+		return checkDocuments(flags.Args(), cfg, stdout, stderr)
+	}
+
+	if opts.reformat {
+		return reformatDocuments(flags.Args(), cfg, stdin, stdout, stderr)
+	}
+
+	inputs, err := expandInputs(flags.Args())
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md:", err)
+		return exitIOError
+	}
+
+	out := stdout
+
+	if opts.output != "" {
+		file, err := os.Create(opts.output)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitIOError
+		}
+
+		defer file.Close()
+		out = file
+	}
+
+	for idx, input := range inputs {
+		// separate consecutive tables with an empty line
+		if idx > 0 {
+			if _, err := io.WriteString(out, "\n"); err != nil {
+				fmt.Fprintln(stderr, "csv2md:", err)
+				return exitIOError
+			}
+		}
+
+		if code := convertInput(input, stdin, out, cfg, stderr); code != exitOK {
+			return code
+		}
+	}
+
+	return exitOK
+}
+
+// Convert a single input, "-" being the standard input, and return the exit code
+func convertInput(input string, stdin io.Reader, out io.Writer, cfg csv2mdtable.Config, stderr io.Writer) int {
+	var r io.Reader = stdin
+
+	if input != "-" {
+		file, err := os.Open(input)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitIOError
+		}
+
+		defer file.Close()
+		r = file
+	}
+
+	report, err := csv2mdtable.ConvertReaderWithReport(r, out, cfg)
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(stderr, "csv2md: %s: warning: %s\n", displayName(input), warning)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "csv2md: %s: %s\n", displayName(input), err)
+		return exitCodeOf(err)
+	}
+
+	return exitOK
+}
+
+// Get the exit code for an error of a conversion
+func exitCodeOf(err error) int {
+	var cfgErr *csv2mdtable.ConfigError
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return exitIOError
+	case errors.As(err, &cfgErr):
+		return exitConfigError
+	}
+
+	return exitParseError
+}
+
+// Regenerate the marked tables of the Markdown documents and return the exit code
+func updateDocuments(args []string, cfg csv2mdtable.Config, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "csv2md: -update requires at least one Markdown file")
+		return exitConfigError
+	}
+
+	paths, err := expandInputs(args)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md:", err)
+		return exitIOError
+	}
+
+	for _, path := range paths {
+		changed, err := csv2mdtable.UpdateMarkdownFile(path, cfg)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitCodeOf(err)
+		}
+
+		if changed {
+			fmt.Fprintln(stdout, "updated", path)
+		}
+	}
+
+	return exitOK
+}
+
+// Report the marked tables of the Markdown documents that are out of date and return the exit code
+func checkDocuments(args []string, cfg csv2mdtable.Config, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "csv2md: -check requires at least one Markdown file")
+		return exitConfigError
+	}
+
+	paths, err := expandInputs(args)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md:", err)
+		return exitIOError
+	}
+
+	stale, err := csv2mdtable.CheckMarkdownFiles(paths, cfg)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md:", err)
+		return exitCodeOf(err)
+	}
+
+	for _, table := range stale {
+		fmt.Fprintf(stderr, "%s:%d: table generated from %s is out of date\n", table.Path, table.Line, table.Src)
+		fmt.Fprint(stdout, table.Diff)
+	}
+
+	if len(stale) > 0 {
+		return exitStale
+	}
+
+	return exitOK
+}
+
+// Re-render the pipe tables of the Markdown documents, or of the standard input, and return the exit code
+func reformatDocuments(args []string, cfg csv2mdtable.Config, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		doc, err := io.ReadAll(stdin)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitIOError
+		}
+
+		formatted, err := csv2mdtable.FormatMarkdown(doc, cfg)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md: <stdin>:", err)
+			return exitParseError
+		}
+
+		if _, err := stdout.Write(formatted); err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitIOError
+		}
+
+		return exitOK
+	}
+
+	paths, err := expandInputs(args)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "csv2md:", err)
+		return exitIOError
+	}
+
+	for _, path := range paths {
+		changed, err := csv2mdtable.FormatMarkdownFile(path, cfg)
+
+		if err != nil {
+			fmt.Fprintln(stderr, "csv2md:", err)
+			return exitCodeOf(err)
+		}
+
+		if changed {
+			fmt.Fprintln(stdout, "formatted", path)
+		}
+	}
+
+	return exitOK
+}
+
+// Count the values that are true
+func countTrue(values ...bool) int {
+	count := 0
+	for _, val := range values {
+		if val {
+			count++
+		}
+	}
+	return count
+}
+
+// Expand glob patterns into the files they match. No argument means the standard input.
+func expandInputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"-"}, nil
+	}
+
+	var inputs []string
+
+	for _, arg := range args {
+		if arg == "-" || !strings.ContainsAny(arg, "*?[") {
+			inputs = append(inputs, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %q", arg)
+		}
+
+		inputs = append(inputs, matches...)
+	}
+
+	return inputs, nil
+}
+
+// Name of the input used in error messages
+func displayName(input string) string {
+	if input == "-" {
+		return "<stdin>"
+	}
+	return input
+}
+
+// Values of the command line flags
+type options struct {
+	align            string
+	autoAlign        bool
+	caption          string
+	columnAlign      mapFlag
+	columnIndexAlign mapFlag
+	compact          bool
+	displayWidth     bool
+	delimiter        string
+	comment          string
+	fieldsPerRecord  int
+	lazyQuotes       bool
+	trimLeadingSpace bool
+	reuseRecord      bool
+	ragged           string
+	raggedFill       string
+	lenient          bool
+	maxWarnings      int
+	exclude          listFlag
+	excludeIndex     listFlag
+	include          listFlag
+	order            listFlag
+	alias            mapFlag
+	header           string
+	headerNames      listFlag
+	sort             string
+	sortRows         listFlag
+	filter           string
+	newlines         string
+	duplicateHeaders string
+	sampleRows       int
+	verbose          bool
+	output           string
+	to               string
+	htmlClass        string
+	htmlAlignAttr    bool
+	latexBooktabs    bool
+	latexFloat       bool
+	headerStyle      string
+	maxWidth         int
+	truncate         bool
+	update           bool
+	check            bool
+	reformat         bool
+	preserveAlign    bool
+}
+
+// Register the flags on the flag set
+func newOptions(flags *flag.FlagSet) *options {
+	opts := &options{}
+
+	flags.StringVar(&opts.align, "align", "center", "alignment of the table: left, center or right")
+	flags.BoolVar(&opts.autoAlign, "auto-align", false, "detect the alignment of each column from its values")
+	flags.StringVar(&opts.caption, "caption", "", "caption of the table, rendered as an HTML comment")
+	flags.Var(&opts.columnAlign, "column-align", "alignment of a column by header name, as `name=align` (repeatable)")
+	flags.Var(&opts.columnIndexAlign, "column-index-align", "alignment of a column by index starting at 0, as `index=align` (repeatable)")
+	flags.BoolVar(&opts.compact, "compact", false, "write the compact version of the table")
+	flags.BoolVar(&opts.displayWidth, "display-width", false, "pad columns by display width, for CJK characters, emoji and combining marks")
+	flags.StringVar(&opts.delimiter, "delimiter", ",", "field delimiter of the CSV, a single character or \"tab\"")
+	flags.StringVar(&opts.comment, "comment", "", "lines beginning with this character are ignored")
+	flags.IntVar(&opts.fieldsPerRecord, "fields-per-record", 0, "number of expected fields per record, 0 uses the field count of the header")
+	flags.BoolVar(&opts.lazyQuotes, "lazy-quotes", false, "allow quotes in unquoted fields and non-doubled quotes in quoted fields")
+	flags.BoolVar(&opts.trimLeadingSpace, "trim-leading-space", false, "ignore leading white space in fields")
+	flags.BoolVar(&opts.reuseRecord, "reuse-record", false, "let the CSV reader reuse the memory of records")
+	flags.StringVar(&opts.ragged, "ragged", "error", "records with another number of fields than the header: error, pad, truncate or overflow")
+	flags.StringVar(&opts.raggedFill, "ragged-fill", "", "`value` short records are padded with")
+	flags.BoolVar(&opts.lenient, "lenient", false, "repair or skip malformed records and report them as warnings instead of failing")
+	flags.IntVar(&opts.maxWarnings, "max-warnings", 0, "fail after `n` malformed records in lenient mode, 0 tolerates any number")
+	flags.Var(&opts.exclude, "exclude", "`column` to exclude from the table, name#2 for the second column with the name (repeatable)")
+	flags.Var(&opts.excludeIndex, "exclude-index", "`index` of a column to exclude from the table, starting at 0 (repeatable)")
+	flags.Var(&opts.include, "include", "only include this `column` in the table (repeatable)")
+	flags.Var(&opts.order, "order", "position this `column` next, before the columns that are not ordered (repeatable)")
+	flags.Var(&opts.alias, "alias", "display a column under another name, as `name=alias` (repeatable)")
+	flags.StringVar(&opts.header, "header", "first", "header line of the CSV: first (the first record), or for a CSV without one numbered, lettered or empty")
+	flags.Var(&opts.headerNames, "header-name", "name of the next column of a CSV without a header line (repeatable)")
+	flags.StringVar(&opts.sort, "sort", "none", "sort the columns: none, asc or desc")
+	flags.Var(&opts.sortRows, "sort-rows", "sort the rows by a `column[:asc|desc][:string|nocase|natural|numeric|date=layout]` (repeatable, in order of precedence)")
+	flags.StringVar(&opts.filter, "filter", "", "only keep the rows matching the `expression`, e.g. 'Country == \"Chile\" and Amount > 1000'")
+	flags.StringVar(&opts.duplicateHeaders, "duplicate-headers", "keep", "columns sharing a header name: keep, error, suffix or merge")
+	flags.StringVar(&opts.newlines, "newlines", "br", "line breaks in values of Markdown tables: br, space or error")
+	flags.IntVar(&opts.sampleRows, "sample-rows", 0, "measure column widths (and detect -auto-align) on the first `n` rows only, 0 measures every row")
+	flags.BoolVar(&opts.verbose, "verbose", false, "log detailed diagnostic messages")
+	flags.StringVar(&opts.to, "to", "markdown", "output format: markdown, pandoc, html, asciidoc, rst, rst-simple, org, jira, confluence, latex, terminal or ascii")
+	flags.StringVar(&opts.htmlClass, "html-class", "", "CSS `class` of the HTML table")
+	flags.BoolVar(&opts.htmlAlignAttr, "html-align-attribute", false, "align HTML cells with the align attribute instead of a style")
+	flags.BoolVar(&opts.latexBooktabs, "latex-booktabs", false, "draw the rules of the LaTeX table with the booktabs package")
+	flags.BoolVar(&opts.latexFloat, "latex-float", false, "wrap the LaTeX table in a table float")
+	flags.StringVar(&opts.headerStyle, "header-style", "", "ANSI SGR `parameters` of the header of terminal tables, e.g. \"1\" for bold")
+	flags.IntVar(&opts.maxWidth, "max-width", 0, "maximum `width` of terminal tables, 0 uses the width of the terminal and -1 means no limit")
+	flags.BoolVar(&opts.truncate, "truncate", false, "truncate the values of terminal tables that do not fit instead of wrapping them")
+	flags.StringVar(&opts.output, "o", "", "write the output to `path` instead of the standard output")
+	flags.BoolVar(&opts.update, "update", false, "regenerate the tables between csv2md marker comments of the given Markdown files")
+	flags.BoolVar(&opts.reformat, "reformat", false, "re-render every pipe table of the given Markdown files, or of the standard input")
+	flags.BoolVar(&opts.preserveAlign, "preserve-alignment", false, "keep the explicit column alignments of reformatted tables")
+	flags.BoolVar(&opts.check, "check", false, "report the tables between csv2md marker comments of the given Markdown files that are out of date")
+
+	return opts
+}
+
+// Build the config from the flag values
+func (opts *options) config() (csv2mdtable.Config, error) {
+	var cfg csv2mdtable.Config
+	var err error
+
+	if cfg.Align, err = csv2mdtable.ParseAlign(opts.align); err != nil {
+		return cfg, err
+	}
+
+	for name, val := range opts.columnAlign {
+		align, err := csv2mdtable.ParseAlign(val)
+
+		if err != nil {
+			return cfg, err
+		}
+
+		if cfg.ColumnAlign == nil {
+			cfg.ColumnAlign = map[string]csv2mdtable.Align{}
+		}
+		cfg.ColumnAlign[name] = align
+	}
+
+	for key, val := range opts.columnIndexAlign {
+		idx, err := strconv.Atoi(key)
+
+		if err != nil {
+			return cfg, fmt.Errorf("invalid column index %q", key)
+		}
+
+		align, err := csv2mdtable.ParseAlign(val)
+
+		if err != nil {
+			return cfg, err
+		}
+
+		if cfg.ColumnIndexAlign == nil {
+			cfg.ColumnIndexAlign = map[int]csv2mdtable.Align{}
+		}
+		cfg.ColumnIndexAlign[idx] = align
+	}
+
+	if cfg.SortColumns, err = csv2mdtable.ParseColumnSortOption(opts.sort); err != nil {
+		return cfg, err
+	}
+
+	for _, spec := range opts.sortRows {
+		key, err := csv2mdtable.ParseRowSortKey(spec)
+		if err != nil {
+			return cfg, err
+		}
+
+		cfg.SortRows = append(cfg.SortRows, key)
+	}
+
+	if cfg.DuplicateHeaders, err = csv2mdtable.ParseDuplicateHeaderPolicy(opts.duplicateHeaders); err != nil {
+		return cfg, err
+	}
+
+	if cfg.NewlinePolicy, err = csv2mdtable.ParseNewlinePolicy(opts.newlines); err != nil {
+		return cfg, err
+	}
+
+	if cfg.RaggedRows, err = csv2mdtable.ParseRaggedRowPolicy(opts.ragged); err != nil {
+		return cfg, err
+	}
+
+	if cfg.HasHeader, err = csv2mdtable.ParseHeaderPolicy(opts.header); err != nil {
+		return cfg, err
+	}
+
+	// supplied names imply a CSV without a header line
+	if len(opts.headerNames) > 0 && cfg.HasHeader == csv2mdtable.FirstRecordHeader {
+		cfg.HasHeader = csv2mdtable.NumberedHeader
+	}
+
+	if cfg.CSVReaderConfig.Comma, err = csv2mdtable.ParseRune("delimiter", opts.delimiter); err != nil {
+		return cfg, err
+	}
+
+	if opts.comment != "" {
+		if cfg.CSVReaderConfig.Comment, err = csv2mdtable.ParseRune("comment", opts.comment); err != nil {
+			return cfg, err
+		}
+	}
+
+	cfg.AutoAlign = opts.autoAlign
+	cfg.Caption = opts.caption
+	cfg.RowFilterExpression = opts.filter
+	cfg.HeaderNames = opts.headerNames
+	cfg.Compact = opts.compact
+	cfg.CSVReaderConfig.FieldsPerRecord = opts.fieldsPerRecord
+	cfg.CSVReaderConfig.LazyQuotes = opts.lazyQuotes
+	cfg.CSVReaderConfig.TrimLeadingSpace = opts.trimLeadingSpace
+	cfg.CSVReaderConfig.ReuseRecord = opts.reuseRecord
+	cfg.RaggedRowFill = opts.raggedFill
+	cfg.Lenient = opts.lenient
+	cfg.MaxWarnings = opts.maxWarnings
+	cfg.ExcludedColumns = opts.exclude
+
+	for _, val := range opts.excludeIndex {
+		idx, err := strconv.Atoi(val)
+
+		if err != nil {
+			return cfg, fmt.Errorf("invalid column index %q", val)
+		}
+
+		cfg.ExcludedColumnIndices = append(cfg.ExcludedColumnIndices, idx)
+	}
+
+	cfg.IncludedColumns = opts.include
+	cfg.ColumnOrder = opts.order
+
+	if len(opts.alias) > 0 {
+		cfg.ColumnAliases = opts.alias
+	}
+	cfg.PreserveAlignment = opts.preserveAlign
+	cfg.StreamWidthSampleRows = opts.sampleRows
+	cfg.VerboseLogging = opts.verbose
+
+	if opts.displayWidth {
+		cfg.WidthMode = csv2mdtable.DisplayWidth
+	}
+
+	if cfg.Renderer, err = opts.renderer(cfg.WidthMode); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// Get the renderer of the output format, nil meaning the Markdown table
+func (opts *options) renderer(widthMode csv2mdtable.WidthMode) (csv2mdtable.Renderer, error) {
+	switch strings.ToLower(opts.to) {
+	case "markdown", "md", "":
+		return nil, nil
+	case "html":
+		return csv2mdtable.HTMLRenderer{TableClass: opts.htmlClass, AlignAttribute: opts.htmlAlignAttr}, nil
+	case "asciidoc", "adoc":
+		return csv2mdtable.AsciiDocRenderer{}, nil
+	case "rst", "rst-grid":
+		return csv2mdtable.RSTGridTableRenderer{WidthMode: widthMode}, nil
+	case "rst-simple":
+		return csv2mdtable.RSTSimpleTableRenderer{WidthMode: widthMode}, nil
+	case "org":
+		return csv2mdtable.OrgTableRenderer{WidthMode: widthMode}, nil
+	case "jira":
+		return csv2mdtable.JiraWikiRenderer{}, nil
+	case "confluence":
+		return csv2mdtable.ConfluenceStorageRenderer{}, nil
+	case "pandoc", "grid":
+		return csv2mdtable.PandocGridTableRenderer{WidthMode: widthMode}, nil
+	case "latex", "tex":
+		return csv2mdtable.LaTeXRenderer{Booktabs: opts.latexBooktabs, Float: opts.latexFloat}, nil
+	case "terminal", "ascii":
+		renderer := csv2mdtable.TerminalTableRenderer{HeaderStyle: opts.headerStyle, MaxWidth: opts.maxWidth, WidthMode: widthMode}
+
+		if strings.ToLower(opts.to) == "ascii" {
+			renderer.Style = csv2mdtable.ASCIIBoxStyle
+		}
+
+		if opts.truncate {
+			renderer.Overflow = csv2mdtable.TruncateCells
+		}
+
+		return renderer, nil
+	}
+
+	return nil, fmt.Errorf("unknown output format %q", opts.to)
+}
+
+// A flag that can be repeated, collecting every value
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(val string) error {
+	*l = append(*l, val)
+	return nil
+}
+
+// A repeatable flag of key=value pairs
+type mapFlag map[string]string
+
+func (m *mapFlag) String() string {
+	var pairs []string
+	for key, val := range *m {
+		pairs = append(pairs, key+"="+val)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *mapFlag) Set(val string) error {
+	// split on the last equal sign, column names may contain one
+	idx := strings.LastIndex(val, "=")
+
+	if idx < 0 {
+		return fmt.Errorf("expected key=value, got %q", val)
+	}
+
+	if *m == nil {
+		*m = mapFlag{}
+	}
+	(*m)[val[:idx]] = val[idx+1:]
+
+	return nil
+}
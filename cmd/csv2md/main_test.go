@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const csvString = `First name,Last name,Email
+Jane,Smith,jane.smith@email.com
+John,Doe,john.doe@email.com`
+
+func TestRunStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	expected := `| First name | Last name |
+| :--------- | :-------- |
+| Jane       | Smith     |
+| John       | Doe       |
+`
+
+	code := run([]string{"--align", "left", "--exclude", "Email"}, strings.NewReader(csvString), &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	assert.Equal(t, expected, stdout.String(), "The table should be written to the standard output")
+}
+
+func TestRunExcludeIndex(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"--compact", "--exclude-index", "0", "--exclude-index", "2"}, strings.NewReader(csvString), &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	assert.Equal(t, "|Last name|\n|:-:|\n|Smith|\n|Doe|\n", stdout.String(), "The columns should be excluded by index")
+}
+
+func TestRunFilesAndGlobs(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("A\n1"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "b.csv"), []byte("B;C\n2;3"), 0o644))
+
+	output := filepath.Join(dir, "out.md")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--compact", "--delimiter", ";", "-o", output, filepath.Join(dir, "b.csv"), filepath.Join(dir, "a*.csv")}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	written, err := os.ReadFile(output)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "|B|C|\n|:-:|:-:|\n|2|3|\n\n|A|\n|:-:|\n|1|\n", string(written), "Both tables should be written to the output file")
+	assert.Empty(t, stdout.String(), "Nothing should be written to the standard output")
+}
+
+func TestRunExitCodes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, exitConfigError, run([]string{"--align", "diagonal"}, strings.NewReader(csvString), &stdout, &stderr), "Unknown alignment is a configuration error")
+	assert.Equal(t, exitConfigError, run([]string{"--delimiter", ";;"}, strings.NewReader(csvString), &stdout, &stderr), "Multi-character delimiter is a configuration error")
+	assert.Equal(t, exitConfigError, run([]string{"--no-such-flag"}, strings.NewReader(csvString), &stdout, &stderr), "Unknown flag is a configuration error")
+	assert.Equal(t, exitConfigError, run([]string{"--include", "nope"}, strings.NewReader(csvString), &stdout, &stderr), "Unknown column is a configuration error")
+	assert.Equal(t, exitConfigError, run([]string{"--exclude-index", "3"}, strings.NewReader(csvString), &stdout, &stderr), "Unknown column index is a configuration error")
+	assert.Equal(t, exitParseError, run(nil, strings.NewReader("a,b\n1,2,3"), &stdout, &stderr), "Malformed CSV is a parse error")
+	assert.Equal(t, exitIOError, run([]string{filepath.Join(t.TempDir(), "missing.csv")}, nil, &stdout, &stderr), "Missing file is an I/O error")
+}
+
+func TestRunUpdate(t *testing.T) {
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.md")
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("A\n1"), 0o644))
+	assert.Nil(t, os.WriteFile(doc, []byte("Text\n<!-- csv2md src=a.csv -->\n<!-- /csv2md -->\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--update", "--compact", filepath.Join(dir, "*.md")}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	written, err := os.ReadFile(doc)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Text\n<!-- csv2md src=a.csv -->\n|A|\n|:-:|\n|1|\n<!-- /csv2md -->\n", string(written), "The table should be regenerated in place")
+	assert.Equal(t, "updated "+doc+"\n", stdout.String(), "Updated files should be listed")
+}
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.md")
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("A\n1"), 0o644))
+	assert.Nil(t, os.WriteFile(doc, []byte("<!-- csv2md src=a.csv compact -->\n|A|\n|:-:|\n|2|\n<!-- /csv2md -->\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--check", doc}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitStale, code, "A stale table should fail the check")
+	assert.Contains(t, stdout.String(), "-|2|\n+|1|\n", "The diff should be printed")
+
+	assert.Equal(t, exitOK, run([]string{"--update", doc}, nil, &stdout, &stderr), stderr.String())
+	assert.Equal(t, exitOK, run([]string{"--check", doc}, nil, &stdout, &stderr), "An updated table should pass the check")
+}
+
+func TestRunReformat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"--reformat", "--compact"}, strings.NewReader("Text\n\n| a | b |\n| --- | --- |\n| 1 | 2 |\n"), &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	assert.Equal(t, "Text\n\n|a|b|\n|:-:|:-:|\n|1|2|\n", stdout.String(), "The reformatted document should be written to the standard output")
+
+	assert.Equal(t, exitConfigError, run([]string{"--reformat", "--check", "doc.md"}, nil, &stdout, &stderr), "Modes cannot be combined")
+}
+
+func TestRunTerminalTable(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	expected := `+-------+-------+
+| First | Last  |
+| name  | name  |
++-------+-------+
+| Jane  | Smith |
+| John  | Doe   |
++-------+-------+
+`
+
+	code := run([]string{"-to", "ascii", "--max-width", "17", "--align", "left", "--exclude", "Email"}, strings.NewReader(csvString), &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+
+	assert.Equal(t, expected, stdout.String(), "The table should be shrunk to the maximum width")
+}
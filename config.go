@@ -1,10 +1,12 @@
 package csv2mdtable
 
 import (
-	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
 	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 type Align int
@@ -26,13 +28,96 @@ const (
 	Custom     ColumnSortOption = 3
 )
 
+type NewlinePolicy int
+
+const (
+	// Replace line breaks in values with <br> elements
+	NewlineToBreak NewlinePolicy = 0
+
+	// Replace line breaks in values with spaces
+	NewlineToSpace NewlinePolicy = 1
+
+	// Fail the conversion if a value contains a line break
+	NewlineError NewlinePolicy = 2
+)
+
+type DuplicateHeaderPolicy int
+
+const (
+	// Keep columns sharing a header name as separate columns
+	KeepDuplicateHeaders DuplicateHeaderPolicy = 0
+
+	// Fail the conversion if columns share a header name
+	ErrorOnDuplicateHeaders DuplicateHeaderPolicy = 1
+
+	// Rename the second, third... column sharing a header name by appending a number: Notes, Notes_2, Notes_3
+	SuffixDuplicateHeaders DuplicateHeaderPolicy = 2
+
+	// Merge columns sharing a header name into a single column, joining their non-empty values with "; "
+	MergeDuplicateHeaders DuplicateHeaderPolicy = 3
+)
+
+type HeaderPolicy int
+
+const (
+	// The first record of the CSV is the header line
+	FirstRecordHeader HeaderPolicy = 0
+
+	// The CSV has no header line, the columns are named Column 1, Column 2, Column 3...
+	NumberedHeader HeaderPolicy = 1
+
+	// The CSV has no header line, the columns are named like the columns of a spreadsheet: A, B, ..., Z, AA, AB...
+	LetteredHeader HeaderPolicy = 2
+
+	// The CSV has no header line and the table is rendered with an empty header row.
+	// The config refers to the columns as Column 1, Column 2, Column 3...
+	EmptyHeader HeaderPolicy = 3
+)
+
+type RaggedRowPolicy int
+
+const (
+	// Fail the conversion if a record has another number of fields than the header line, with the line of the record
+	RaggedRowsError RaggedRowPolicy = 0
+
+	// Pad short records with RaggedRowFill. Long records fail the conversion.
+	PadRaggedRows RaggedRowPolicy = 1
+
+	// Pad short records with RaggedRowFill and drop the extra values of long records
+	TruncateRaggedRows RaggedRowPolicy = 2
+
+	// Pad short records with RaggedRowFill and fold the extra values of long records, joined with "; ",
+	// into an additional column named Overflow (which can be renamed with ColumnAliases)
+	OverflowRaggedRows RaggedRowPolicy = 3
+)
+
 type Config struct {
 	// Align the rendered content for the Markdown table. 0 = Center, 1 = Left, 2 = Right
 	Align Align
 
+	// Detect the alignment of each column from its values: numbers, amounts of money and percentages
+	// are aligned right, boolean-like values are centered and text is aligned left.
+	// Takes precedence over Align, but not over ColumnAlign and ColumnIndexAlign.
+	AutoAlign bool
+
 	// Caption of the table (as an HTML comment)
 	Caption string
 
+	// Alignment of specific columns, by header name. Takes precedence over every other alignment setting.
+	ColumnAlign map[string]Align
+
+	// Names to display instead of the header names of specific columns, e.g. "cust_id" → "Customer ID".
+	// Every other option refers to the columns by their header name in the CSV.
+	ColumnAliases map[string]string
+
+	// Alignment of specific columns, by the index of the column in the CSV (starting at 0).
+	// Takes precedence over AutoAlign and Align.
+	ColumnIndexAlign map[int]Align
+
+	// Names of the columns positioned first, exactly in this order. The columns that are not listed follow,
+	// in the order set by SortColumns.
+	ColumnOrder []string
+
 	// Should the markdown table be the compact version
 	Compact bool
 
@@ -40,14 +125,65 @@ type Config struct {
 	// See also https://pkg.go.dev/encoding/csv#Reader
 	CSVReaderConfig CSVReaderConfig
 
-	// List of columns to be excluded from table construction
+	// What happens to columns sharing a header name
+	DuplicateHeaders DuplicateHeaderPolicy
+
+	// Indices of columns to be excluded from table construction, starting at 0
+	ExcludedColumnIndices []int
+
+	// List of columns to be excluded from table construction. A name excludes every column with this header name,
+	// name#2 only the second one.
 	ExcludedColumns []string
 
-	// Indices of excluded columns (internal)
-	excludedColumnsIndices []int
+	// Whether the first record of the CSV is the header line, and how the columns are named if it is not.
+	// Column exclusion, sorting and the other options refer to the columns by the generated names.
+	HasHeader HeaderPolicy
+
+	// Names of the columns of a CSV without a header line, used instead of the generated names.
+	// Columns without a name here are named according to HasHeader.
+	HeaderNames []string
+
+	// List of the only columns to be included in table construction. Every column is included if empty.
+	// ExcludedColumns is applied to the included columns.
+	IncludedColumns []string
+
+	// Repair or skip malformed records instead of failing the conversion. Records with the wrong number of fields
+	// are padded with empty values or lose their extra values, records that cannot be read (e.g. because of a bare quote)
+	// are skipped. Every malformed record is listed in the report of ParseWithReport, ConvertWithReport and ConvertReaderWithReport.
+	Lenient bool
+
+	// Logger the warnings and diagnostics of the conversion are written to. Defaults to slog.Default().
+	// Global logging settings are never changed, see VerboseLogging.
+	Logger *slog.Logger
+
+	// Number of malformed records tolerated in lenient mode before the conversion fails with ErrTooManyWarnings.
+	// 0 tolerates any number.
+	MaxWarnings int
 
-	// Indices of columns to convert to
-	orderedColumnsIndices []int
+	// What happens to line breaks in values, which cannot be written in a Markdown pipe table
+	NewlinePolicy NewlinePolicy
+
+	// Keep the explicit alignments of the delimiter rows when reformatting tables with FormatMarkdown.
+	// Columns without an explicit alignment are aligned according to the other alignment settings.
+	PreserveAlignment bool
+
+	// Value short records are padded with, see RaggedRows
+	RaggedRowFill string
+
+	// What happens to records with another number of fields than the header line (or CSVReaderConfig.FieldsPerRecord)
+	RaggedRows RaggedRowPolicy
+
+	// Renderer used to write the parsed table. Defaults to a PipeTableRenderer,
+	// or a CompactPipeTableRenderer if Compact is set.
+	Renderer Renderer
+
+	// Decides which data rows are kept. Rows the function returns false for are left out of the table,
+	// before the rows are sorted. Combined with RowFilterExpression, a row must satisfy both.
+	RowFilter func(row Row) bool
+
+	// Expression deciding which data rows are kept, e.g. Country == "Chile" and Amount >= 1000.
+	// See the readme for the syntax.
+	RowFilterExpression string
 
 	// Should the columns be sorted and how?
 	SortColumns ColumnSortOption
@@ -55,7 +191,21 @@ type Config struct {
 	// Custom sort function
 	SortFunction ColumnSortFunction
 
-	// Log detailed diagnostic messages when running the program.
+	// Keys the data rows are sorted by, in order of precedence. Rows with equal keys keep their order.
+	// ConvertReader holds the whole table in memory to sort it.
+	SortRows []RowSortKey
+
+	// Number of data rows ConvertReader inspects to determine the column widths of a beautified table,
+	// and the alignments of AutoAlign, which then ignores the values of later rows.
+	// 0 measures every row, reading the input twice or spooling it to a temporary file.
+	StreamWidthSampleRows int
+
+	// How the width of the values is measured to pad the columns of the beautified table.
+	// DisplayWidth keeps tables with CJK characters, emoji and combining marks aligned in monospace editors.
+	WidthMode WidthMode
+
+	// Log detailed diagnostic messages, such as the number of rows, the excluded columns and the column widths,
+	// by letting the debug records through to Logger. The level of Logger itself is left alone.
 	VerboseLogging bool
 }
 
@@ -76,12 +226,9 @@ type CSVReaderConfig struct {
 	// It must also not be equal to Comma.
 	Comment rune
 
-	// FieldsPerRecord is the number of expected fields per record.
-	// If FieldsPerRecord is positive, Read requires each record to
-	// have the given number of fields. If FieldsPerRecord is 0, Read sets it to
-	// the number of fields in the first record, so that future records must
-	// have the same field count. If FieldsPerRecord is negative, no check is
-	// made and records may have a variable number of fields.
+	// FieldsPerRecord is the number of expected fields per record. A positive value is the field count
+	// every record must have. 0 uses the field count of the first record, negative values allow records
+	// of any length; mismatches are handled by RaggedRows.
 	FieldsPerRecord int
 
 	// If LazyQuotes is true, a quote may appear in an unquoted field and a
@@ -99,38 +246,116 @@ type CSVReaderConfig struct {
 }
 
 // Validate the Config object passed as parameter.
-// An error will be returned in case the configuration was invalid.
+// A *ConfigError naming the offending option will be returned in case the configuration was invalid.
 func ValidateConfig(cfg Config) error {
+	_, err := validateConfig(cfg)
+	return err
+}
+
+// Validate the config, see ValidateConfig. Also returns the parsed RowFilterExpression, nil if there is none,
+// so it does not have to be parsed again for the conversion.
+func validateConfig(cfg Config) (filterNode, error) {
 	configMalformed := false
 
-	if cfg.VerboseLogging {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-		slog.Debug("Validating config 🤔")
-	}
+	logger := loggerFor(cfg)
+	logger.Debug("Validating config 🤔")
 
 	if cfg.Align < Center || cfg.Align > Right {
-		return errors.New("align value is out of range, please choose in range [0-2]")
+		return nil, configErrorf("Align", "align value is out of range, please choose in range [0-2]")
+	}
+
+	for name, align := range cfg.ColumnAlign {
+		if align < Center || align > Right {
+			return nil, configErrorf("ColumnAlign", "align value of column %s is out of range, please choose in range [0-2]", name)
+		}
+	}
+
+	for idx, align := range cfg.ColumnIndexAlign {
+		if idx < 0 {
+			return nil, configErrorf("ColumnIndexAlign", "column index %d in ColumnIndexAlign must not be negative", idx)
+		}
+
+		if align < Center || align > Right {
+			return nil, configErrorf("ColumnIndexAlign", "align value of column %d is out of range, please choose in range [0-2]", idx)
+		}
+	}
+
+	if cfg.DuplicateHeaders < KeepDuplicateHeaders || cfg.DuplicateHeaders > MergeDuplicateHeaders {
+		return nil, configErrorf("DuplicateHeaders", "duplicate headers value is out of range, please choose in range [0-3]")
+	}
+
+	if cfg.HasHeader < FirstRecordHeader || cfg.HasHeader > EmptyHeader {
+		return nil, configErrorf("HasHeader", "has header value is out of range, please choose in range [0-3]")
+	}
+
+	if cfg.HasHeader == FirstRecordHeader && len(cfg.HeaderNames) > 0 {
+		return nil, configErrorf("HeaderNames", "HeaderNames are only used for a CSV without a header line, set HasHeader as well")
+	}
+
+	for _, idx := range cfg.ExcludedColumnIndices {
+		if idx < 0 {
+			return nil, configErrorf("ExcludedColumnIndices", "column index %d in ExcludedColumnIndices must not be negative", idx)
+		}
+	}
+
+	if name, ok := findDuplicate(cfg.ColumnOrder); ok {
+		return nil, configErrorf("ColumnOrder", "column %s is listed more than once in ColumnOrder", name)
 	}
 
 	if cfg.SortColumns < None || cfg.SortColumns > Custom {
-		return errors.New("sort columns value is out of range, please choose in range [0-3]")
+		return nil, configErrorf("SortColumns", "sort columns value is out of range, please choose in range [0-3]")
+	}
+
+	if cfg.NewlinePolicy < NewlineToBreak || cfg.NewlinePolicy > NewlineError {
+		return nil, configErrorf("NewlinePolicy", "newline policy value is out of range, please choose in range [0-2]")
+	}
+
+	var rowFilter filterNode
+
+	if cfg.RowFilterExpression != "" {
+		var err error
+		if rowFilter, err = parseRowFilterExpression(cfg.RowFilterExpression); err != nil {
+			return nil, configErrorf("RowFilterExpression", "invalid RowFilterExpression: %w", err)
+		}
+	}
+
+	for idx, key := range cfg.SortRows {
+		if err := validateRowSortKey(key, idx); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.WidthMode < RuneCountWidth || cfg.WidthMode > DisplayWidth {
+		return nil, configErrorf("WidthMode", "width mode value is out of range, please choose in range [0-1]")
+	}
+
+	if cfg.RaggedRows < RaggedRowsError || cfg.RaggedRows > OverflowRaggedRows {
+		return nil, configErrorf("RaggedRows", "ragged rows value is out of range, please choose in range [0-3]")
+	}
+
+	if cfg.MaxWarnings < 0 {
+		return nil, configErrorf("MaxWarnings", "max warnings must not be negative")
+	}
+
+	if cfg.StreamWidthSampleRows < 0 {
+		return nil, configErrorf("StreamWidthSampleRows", "stream width sample rows must not be negative")
 	}
 
 	if cfg.SortColumns == Custom && cfg.SortFunction == nil {
-		return errors.New("sort type is set to Custom but SortFunc was not set.")
+		return nil, configErrorf("SortFunction", "sort type is set to Custom but SortFunc was not set.")
 	}
 
 	// function passed in but not sort type is not custom
 	if cfg.SortColumns != Custom && cfg.SortFunction != nil {
 		configMalformed = true
-		slog.Warn("Sort function only works when SortColumns is set to Custom. SortColumns received is " + sortColumnsToString(cfg.SortColumns) + ", ignoring SortFunc.")
+		logger.Warn("Sort function only works when SortColumns is set to Custom, ignoring SortFunc", "sort_columns", sortColumnsToString(cfg.SortColumns))
 	}
 
-	if cfg.VerboseLogging && !configMalformed {
-		slog.Debug("Config is valid ✅")
+	if !configMalformed {
+		logger.Debug("Config is valid ✅")
 	}
 
-	return nil
+	return rowFilter, nil
 }
 
 func sortColumnsToString(val ColumnSortOption) string {
@@ -148,41 +373,217 @@ func sortColumnsToString(val ColumnSortOption) string {
 	return ""
 }
 
-// Populate orderColumnIndices in Config object
-func populateColumnIndices(cfg Config, headerLine []string) Config {
+// Parse the name of an alignment: left, center (or centre) and right, case-insensitive.
+// The first letter of each name is accepted as well.
+func ParseAlign(val string) (Align, error) {
+	switch strings.ToLower(val) {
+	case "center", "centre", "c":
+		return Center, nil
+	case "left", "l":
+		return Left, nil
+	case "right", "r":
+		return Right, nil
+	}
+
+	return Center, fmt.Errorf("unknown alignment %q, please choose left, center or right", val)
+}
+
+// Parse an option that must be a single character, such as the delimiter or the comment character of the CSV.
+// "tab" and "\t" stand for the tab character. name is the name of the option, used in the error.
+func ParseRune(name string, val string) (rune, error) {
+	if val == "tab" || val == `\t` {
+		return '\t', nil
+	}
+
+	if utf8.RuneCountInString(val) != 1 {
+		return 0, fmt.Errorf("%s must be a single character, got %q", name, val)
+	}
+
+	r, _ := utf8.DecodeRuneInString(val)
+	return r, nil
+}
+
+// Parse the name of a column sort option: none, asc (or ascending) and desc (or descending), case-insensitive.
+// Custom cannot be parsed, as it requires a sort function.
+func ParseColumnSortOption(val string) (ColumnSortOption, error) {
+	switch strings.ToLower(val) {
+	case "none", "":
+		return None, nil
+	case "asc", "ascending":
+		return Ascending, nil
+	case "desc", "descending":
+		return Descending, nil
+	}
+
+	return None, fmt.Errorf("unknown sort option %q, please choose none, asc or desc", val)
+}
+
+// Parse the name of a newline policy: br, space and error, case-insensitive
+func ParseNewlinePolicy(val string) (NewlinePolicy, error) {
+	switch strings.ToLower(val) {
+	case "br", "":
+		return NewlineToBreak, nil
+	case "space":
+		return NewlineToSpace, nil
+	case "error":
+		return NewlineError, nil
+	}
+
+	return NewlineToBreak, fmt.Errorf("unknown newline policy %q, please choose br, space or error", val)
+}
+
+// Parse the name of a duplicate header policy: keep, error, suffix and merge, case-insensitive
+func ParseDuplicateHeaderPolicy(val string) (DuplicateHeaderPolicy, error) {
+	switch strings.ToLower(val) {
+	case "keep", "":
+		return KeepDuplicateHeaders, nil
+	case "error":
+		return ErrorOnDuplicateHeaders, nil
+	case "suffix":
+		return SuffixDuplicateHeaders, nil
+	case "merge":
+		return MergeDuplicateHeaders, nil
+	}
+
+	return KeepDuplicateHeaders, fmt.Errorf("unknown duplicate header policy %q, please choose keep, error, suffix or merge", val)
+}
+
+// Parse the name of a ragged row policy: error, pad, truncate and overflow, case-insensitive
+func ParseRaggedRowPolicy(val string) (RaggedRowPolicy, error) {
+	switch strings.ToLower(val) {
+	case "error", "":
+		return RaggedRowsError, nil
+	case "pad":
+		return PadRaggedRows, nil
+	case "truncate":
+		return TruncateRaggedRows, nil
+	case "overflow":
+		return OverflowRaggedRows, nil
+	}
+
+	return RaggedRowsError, fmt.Errorf("unknown ragged row policy %q, please choose error, pad, truncate or overflow", val)
+}
+
+// Parse the name of a header policy: first, numbered, lettered and empty, case-insensitive
+func ParseHeaderPolicy(val string) (HeaderPolicy, error) {
+	switch strings.ToLower(val) {
+	case "first", "":
+		return FirstRecordHeader, nil
+	case "numbered":
+		return NumberedHeader, nil
+	case "lettered":
+		return LetteredHeader, nil
+	case "empty":
+		return EmptyHeader, nil
+	}
+
+	return FirstRecordHeader, fmt.Errorf("unknown header policy %q, please choose first, numbered, lettered or empty", val)
+}
+
+// Get the indices of the header line's columns in the order they are converted: the columns of ColumnOrder first, then the other columns
+// (sorted if SortColumns is set), leaving out the columns that are not in IncludedColumns.
+// An error is returned if a column named in the config does not exist in the header line.
+func orderColumnIndices(cfg Config, headerLine []string) ([]int, error) {
+	if err := checkColumnNames("IncludedColumns", cfg.IncludedColumns, headerLine); err != nil {
+		return nil, err
+	}
+
+	if err := checkColumnNames("ColumnOrder", cfg.ColumnOrder, headerLine); err != nil {
+		return nil, err
+	}
+
+	if err := checkColumnNames("ColumnAliases", slices.Sorted(maps.Keys(cfg.ColumnAliases)), headerLine); err != nil {
+		return nil, err
+	}
+
+	for _, idx := range cfg.ExcludedColumnIndices {
+		if idx >= len(headerLine) {
+			return nil, configErrorf("ExcludedColumnIndices", "column index %d of ExcludedColumnIndices does not exist in the header line", idx)
+		}
+	}
+
 	// get the new order of columns after sorted, compared to the original order of them.
+	var sortedColumnsIndices []int
 	if cfg.SortColumns == None {
 		for i := range len(headerLine) {
-			cfg.orderedColumnsIndices = append(cfg.orderedColumnsIndices, i)
+			sortedColumnsIndices = append(sortedColumnsIndices, i)
 		}
 	} else {
-		cfg.orderedColumnsIndices = getIndicesAfterSorting(cfg, headerLine)
+		sortedColumnsIndices = getIndicesAfterSorting(cfg, headerLine)
+	}
+
+	var orderedColumnsIndices []int
+	for _, name := range cfg.ColumnOrder {
+		for _, i := range findColumnIndices(name, headerLine) {
+			if !slices.Contains(orderedColumnsIndices, i) {
+				orderedColumnsIndices = append(orderedColumnsIndices, i)
+			}
+		}
+	}
+
+	for _, i := range sortedColumnsIndices {
+		if !slices.Contains(orderedColumnsIndices, i) {
+			orderedColumnsIndices = append(orderedColumnsIndices, i)
+		}
+	}
+
+	if len(cfg.IncludedColumns) > 0 {
+		var includedColumnsIndices []int
+		for _, name := range cfg.IncludedColumns {
+			includedColumnsIndices = append(includedColumnsIndices, findColumnIndices(name, headerLine)...)
+		}
+
+		orderedColumnsIndices = slices.DeleteFunc(orderedColumnsIndices, func(i int) bool {
+			return !slices.Contains(includedColumnsIndices, i)
+		})
+	}
+
+	return orderedColumnsIndices, nil
+}
+
+// Make sure every column named by the option exists in the header line
+func checkColumnNames(option string, names []string, headerLine []string) error {
+	for _, name := range names {
+		if len(findColumnIndices(name, headerLine)) == 0 {
+			return configErrorf(option, "column %q of %s does not exist in the header line", name, option)
+		}
 	}
 
-	return cfg
+	return nil
 }
 
-// Get the indices of columns after sorted
+// Get the first name found more than once in the list
+func findDuplicate(names []string) (string, bool) {
+	for i, name := range names {
+		if slices.Contains(names[:i], name) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// Get the indices of columns after sorted. Columns comparing equal keep their order in the header line.
 func getIndicesAfterSorting(cfg Config, headerLine []string) []int {
-	sortedColumns := make([]string, len(headerLine))
-	copy(sortedColumns, headerLine)
-	var columnsIndicesAfterSorting []int
+	columnsIndicesAfterSorting := make([]int, len(headerLine))
+	for i := range headerLine {
+		columnsIndicesAfterSorting[i] = i
+	}
 
+	// sort the indices rather than the names, so that columns sharing a name are all kept
 	switch cfg.SortColumns {
 	case Ascending:
-		slices.SortFunc(sortedColumns, func(a, b string) int {
-			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		slices.SortStableFunc(columnsIndicesAfterSorting, func(a, b int) int {
+			return strings.Compare(strings.ToLower(headerLine[a]), strings.ToLower(headerLine[b]))
 		})
 	case Descending:
-		slices.SortFunc(sortedColumns, func(a, b string) int {
-			return strings.Compare(strings.ToLower(b), strings.ToLower(a))
+		slices.SortStableFunc(columnsIndicesAfterSorting, func(a, b int) int {
+			return strings.Compare(strings.ToLower(headerLine[b]), strings.ToLower(headerLine[a]))
 		})
 	case Custom:
-		slices.SortFunc(sortedColumns, cfg.SortFunction)
-	}
-
-	for i := range sortedColumns {
-		columnsIndicesAfterSorting = append(columnsIndicesAfterSorting, slices.Index(headerLine, sortedColumns[i]))
+		slices.SortStableFunc(columnsIndicesAfterSorting, func(a, b int) int {
+			return cfg.SortFunction(headerLine[a], headerLine[b])
+		})
 	}
 
 	return columnsIndicesAfterSorting
@@ -0,0 +1,60 @@
+package csv2mdtable
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Renders a table in the Confluence storage format, the XHTML Confluence saves its pages in.
+// The header line is a row of <th> cells in the <tbody>, the way the Confluence editor writes tables,
+// and the caption of the table is written in bold above it.
+type ConfluenceStorageRenderer struct{}
+
+func (r ConfluenceStorageRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r ConfluenceStorageRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	if table.Caption != "" {
+		head.WriteString("<p><strong>" + escapeConfluenceCell(table.Caption) + "</strong></p>\n")
+	}
+
+	head.WriteString("<table>\n")
+	head.WriteString("  <tbody>\n")
+	head.WriteString(r.constructRow("th", table, table.Header()))
+
+	_, err := io.WriteString(w, head.String())
+	return err
+}
+
+func (r ConfluenceStorageRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	_, err := io.WriteString(w, r.constructRow("td", table, row))
+	return err
+}
+
+func (r ConfluenceStorageRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	_, err := io.WriteString(w, "  </tbody>\n</table>\n")
+	return err
+}
+
+// Construct a <tr> element, with a cell element of the given tag holding a paragraph for every value
+func (r ConfluenceStorageRenderer) constructRow(cellTag string, table *Table, row []string) string {
+	var line strings.Builder
+	line.WriteString("    <tr>\n")
+
+	for i, val := range row {
+		fmt.Fprintf(&line, "      <%s><p style=\"text-align: %s;\">%s</p></%s>\n", cellTag, alignName(table.Columns[i].Align), escapeConfluenceCell(val), cellTag)
+	}
+
+	line.WriteString("    </tr>\n")
+
+	return line.String()
+}
+
+// Escape a value for the storage format, which is XML. Line breaks in the value become self-closing <br /> elements.
+func escapeConfluenceCell(val string) string {
+	return strings.ReplaceAll(escapeHTMLCell(val), "<br>", "<br />")
+}
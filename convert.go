@@ -3,173 +3,189 @@ package csv2mdtable
 import (
 	"errors"
 	"fmt"
-	"log/slog"
-	"slices"
+	"io"
 	"strings"
-	"unicode/utf8"
+	"time"
 )
 
 // Convert CSV string into a markdown table. Returns the string representation of the markdown table if converted successfully and an error if failed.
 func Convert(csv string, cfg Config) (string, error) {
+	return convert(csv, cfg, &Report{})
+}
+
+// Convert CSV string into a markdown table, like Convert. Also returns the report of the malformed records that were
+// repaired or skipped in lenient mode (see Config.Lenient), which is returned along with the error if the conversion fails.
+func ConvertWithReport(csv string, cfg Config) (string, Report, error) {
+	report := &Report{}
+	table, err := convert(csv, cfg, report)
+	return table, *report, err
+}
 
-	if csv == "" {
-		return "", fmt.Errorf("csv string is empty")
+// Convert CSV string into a markdown table, adding the warnings of lenient mode to the report
+func convert(csv string, cfg Config, report *Report) (string, error) {
+	rowFilter, err := validateInput(csv, cfg)
+	if err != nil {
+		return "", err
 	}
 
-	cfgErr := ValidateConfig(cfg)
+	return convertRecords(newRecordReader(cfg, strings.NewReader(csv), report), cfg, rowFilter, report)
+}
 
-	if cfgErr != nil {
-		return "", fmt.Errorf("Configuration error: %s\n", cfgErr)
-	}
+// Convert the records of the reader into a markdown table. The config must already be validated, rowFilter is its parsed RowFilterExpression.
+func convertRecords(csvReader *recordReader, cfg Config, rowFilter filterNode, report *Report) (string, error) {
+	logger := loggerFor(cfg)
+
+	table, err := parseRecords(csvReader, cfg, rowFilter, report)
 
-	if cfg.VerboseLogging {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
+	if errors.Is(err, ErrAllColumnsExcluded) {
+		logger.Warn("All columns were excluded from conversion. Returning an empty string")
+		return "", nil
 	}
 
-	// escape pipe characters
-	csv = strings.ReplaceAll(csv, "|", `\|`)
+	if err != nil {
+		return "", err
+	}
 
-	csvReader := createCSVReader(cfg, csv)
+	start := time.Now()
+	renderer := rendererFor(cfg)
 
-	records, readErr := csvReader.ReadAll()
+	var result strings.Builder
 
-	if readErr != nil {
-		return "", fmt.Errorf("Failed to parse CSV. Error: %s", readErr)
+	if err := renderer.Render(&result, table); err != nil {
+		return "", err
 	}
 
-	cfg.excludedColumnsIndices = getIndicesOfExcludedColumns(cfg.ExcludedColumns, records[0])
+	logger.Debug("Rendered table", "renderer", fmt.Sprintf("%T", renderer), "bytes", result.Len(), "duration", time.Since(start))
 
-	if len(cfg.excludedColumnsIndices) > 0 && len(cfg.excludedColumnsIndices) == len(records[0]) {
-		slog.Warn("All columns were excluded from conversion. Returning an empty string")
-		return "", nil
-	}
+	// the table returned as a string does not end with a new line
+	return strings.TrimSuffix(result.String(), "\n"), nil
+}
 
-	cfg = populateColumnIndices(cfg, records[0])
+// Write a single line of the table, followed by a new line character
+func writeLine(w io.Writer, line string) error {
+	_, err := io.WriteString(w, strings.TrimSpace(line)+"\n")
+	return err
+}
 
-	colCount := csvReader.FieldsPerRecord
-	result := ""
+// Write lines of the table with the given indentation, dropping trailing whitespaces
+func writeIndentedLines(w io.Writer, indent string, lines string) error {
+	var indented strings.Builder
 
-	if cfg.Caption != "" {
-		result += fmt.Sprintf("<!-- %s -->\n", cfg.Caption)
+	for line := range strings.Lines(lines) {
+		indented.WriteString(indent + strings.TrimRight(line, " \n") + "\n")
 	}
 
-	// max length of each column so we can beautify the table
-	maxLenOfCol := getMaxColumnLengths(records, cfg.Align)
-
-	// constructing each data line
-	for idx := range len(records) {
-		convertedLine, err := constructDataLine(records[idx], cfg, maxLenOfCol, idx)
+	_, err := io.WriteString(w, indented.String())
+	return err
+}
 
-		if err != nil {
-			return "", err
+// Escape the characters that would break the markdown table. rowIdx starts at 1, 0 being the header line.
+func escapeRecord(record []string, columns []Column, policy NewlinePolicy, rowIdx int) ([]string, error) {
+	escaped := make([]string, len(record))
+	for i := range record {
+		if policy == NewlineError && strings.ContainsAny(record[i], "\r\n") {
+			return nil, &RenderError{
+				Row:        rowIdx,
+				Column:     i + 1,
+				ColumnName: columns[i].Name,
+				Value:      record[i],
+				Err:        errors.New("contains a line break, which cannot be written in a pipe table"),
+			}
 		}
 
-		convertedLine = strings.TrimSpace(convertedLine)
+		escaped[i] = escapeCell(record[i], policy)
+	}
+	return escaped, nil
+}
 
-		// only attach a new line if it's not the last line in the table
-		if idx < len(records)-1 {
-			convertedLine += "\n"
+// Escape the characters of a single value that would break the markdown table. Line breaks are replaced according to the policy.
+// Backslashes are escaped where they would escape the following character of the table: before a pipe, before another
+// backslash and at the end of the value. Other backslashes are left alone, so that Markdown escapes such as \_ keep working.
+func escapeCell(val string, policy NewlinePolicy) string {
+	var escaped strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		switch {
+		case val[i] == '|':
+			escaped.WriteString(`\|`)
+		case val[i] == '\\' && (i+1 == len(val) || val[i+1] == '\\' || val[i+1] == '|'):
+			escaped.WriteString(`\\`)
+		default:
+			escaped.WriteByte(val[i])
 		}
+	}
 
-		// append to result string
-		result += convertedLine
+	val = escaped.String()
 
-		// after first line, we shall get a separator line
-		if idx == 0 {
-			separatorLine := constructSeparatorLine(colCount, maxLenOfCol, cfg)
-			result += separatorLine
-		}
+	switch policy {
+	case NewlineToBreak:
+		return replaceLineBreaks(val, "<br>")
+	case NewlineToSpace:
+		return replaceLineBreaks(val, " ")
 	}
 
-	return result, nil
+	return val
 }
 
-// Construct data line
-func constructDataLine(colVals []string, cfg Config, maxLenOfCol []int, currRowIdx int) (string, error) {
-	if cfg.Compact {
-		return constructCompactDataLine(colVals, cfg)
-	} else {
-		return constructBeautifulDataLine(colVals, cfg, maxLenOfCol, currRowIdx)
-	}
+// Replace every line break of a value
+func replaceLineBreaks(val string, replacement string) string {
+	return strings.Join(splitCellLines(val), replacement)
 }
 
 // Construct a well-formatted data line
-func constructBeautifulDataLine(colVals []string, cfg Config, maxLenOfCol []int, currRowIdx int) (string, error) {
+func constructBeautifulDataLine(colVals []string, columns []Column, maxLenOfCol []int, currRowIdx int, mode WidthMode) (string, error) {
 
-	convertedLine := "| "
+	var convertedLine strings.Builder
+	convertedLine.WriteString("| ")
 
-	for _, i := range cfg.orderedColumnsIndices {
-		// If current column is excluded, ignore it
-		if slices.Contains(cfg.excludedColumnsIndices, i) {
-			continue
-		}
+	for i, column := range columns {
+		// values wider than the column (possible when the widths were sampled) are written as they are
+		colLen := max(maxLenOfCol[i], stringWidth(colVals[i], mode))
 
 		paddedString := ""
 		var err error = nil
 
-		switch cfg.Align {
+		switch column.Align {
 		case Left:
-			paddedString, err = padEnd(colVals[i], maxLenOfCol[i], ' ')
+			paddedString, err = padEnd(colVals[i], colLen, ' ', mode)
 		case Right:
-			paddedString, err = padStart(colVals[i], maxLenOfCol[i], ' ')
+			paddedString, err = padStart(colVals[i], colLen, ' ', mode)
 		case Center:
-			paddedString, err = padCenter(colVals[i], maxLenOfCol[i], ' ')
+			paddedString, err = padCenter(colVals[i], colLen, ' ', mode)
 		}
 
 		if err != nil {
-			return "", errors.New("something happened when padding value " + colVals[i] + " row: " + fmt.Sprint(currRowIdx) +
-				" col: " + fmt.Sprint(i) + ". Error message: " + err.Error())
+			return "", paddingError(colVals[i], column, currRowIdx, i, err)
 		}
 
-		convertedLine += paddedString + " | "
+		convertedLine.WriteString(paddedString + " | ")
 	}
 
-	return convertedLine, nil
+	return convertedLine.String(), nil
 }
 
 // Construct a compact data line
-func constructCompactDataLine(colVals []string, cfg Config) (string, error) {
+func constructCompactDataLine(colVals []string) string {
 
-	convertedLine := "|"
+	var convertedLine strings.Builder
+	convertedLine.WriteString("|")
 
-	for _, i := range cfg.orderedColumnsIndices {
-		// If current column is excluded, ignore it
-		if slices.Contains(cfg.excludedColumnsIndices, i) {
-			continue
-		}
-		convertedLine += colVals[i] + "|"
+	for _, val := range colVals {
+		convertedLine.WriteString(val + "|")
 	}
 
-	return convertedLine, nil
-}
-
-// Construct a separator line between the header line and data lines
-func constructSeparatorLine(colsCount int, maxLenOfCol []int, cfg Config) string {
-	if cfg.Compact {
-		// since we're in compact mode, all columns separator will look alike. We just care about the number of columns included
-		return constructCompactSeparatorLine(colsCount-len(cfg.excludedColumnsIndices), cfg.Align)
-	} else {
-		return constructBeautifulSeparatorLine(cfg, maxLenOfCol)
-	}
+	return convertedLine.String()
 }
 
 // Construct a well-formatted separator line
-func constructBeautifulSeparatorLine(cfg Config, maxLenOfCol []int) string {
+func constructBeautifulSeparatorLine(columns []Column, maxLenOfCol []int) string {
 
 	separatorLine := "| "
 
-	for _, i := range cfg.orderedColumnsIndices {
-		// If current column is excluded, ignore it
-		if slices.Contains(cfg.excludedColumnsIndices, i) {
-			continue
-		}
+	for i, column := range columns {
+		dashes := strings.Repeat("-", maxLenOfCol[i])
 
-		dashes := ""
-		// loop through max length of each column and add dashes
-		for range maxLenOfCol[i] {
-			dashes += "-"
-		}
-		switch cfg.Align {
+		switch column.Align {
 		case Left:
 			// replace the first dash with a colon. This makes the rendered table align text on the left hand side
 			dashes = strings.Replace(dashes, "-", ":", 1)
@@ -199,10 +215,10 @@ func constructBeautifulSeparatorLine(cfg Config, maxLenOfCol []int) string {
 }
 
 // Construct a compact separator line
-func constructCompactSeparatorLine(colCount int, align Align) string {
+func constructCompactSeparatorLine(columns []Column) string {
 	separatorLine := "|"
-	for range colCount {
-		switch align {
+	for _, column := range columns {
+		switch column.Align {
 		case Left:
 			separatorLine += ":-|"
 		case Right:
@@ -219,39 +235,39 @@ func constructCompactSeparatorLine(colCount int, align Align) string {
 	return separatorLine
 }
 
-// Get max length of each columns
-func getMaxColumnLengths(lines [][]string, align Align) []int {
-	maxLens := make([]int, len(lines[0]))
-	for _, fields := range lines {
-		for fieldIdx, fieldVal := range fields {
-			if utf8.RuneCountInString(fieldVal) > maxLens[fieldIdx] {
-				maxLens[fieldIdx] = utf8.RuneCountInString(fieldVal)
-			}
-		}
-	}
+// Get max length of each columns of the table, as rendered by the measurer
+func getMaxColumnLengths(table *Table, measurer columnMeasurer) []int {
+	maxLens := measureColumnLengths(table, measurer)
 
-	for idx, colLen := range maxLens {
-		if colLen <= 2 && align == Center {
-			// if align is center, we need at least 3 spaces (:-:)
-			maxLens[idx] = 3
-		} else if colLen < 2 && align != Center {
-			maxLens[idx] = 2
-		}
+	applyMinimumColumnLengths(maxLens, table.Columns, measurer)
+
+	return maxLens
+}
+
+// Get the width of the widest rendered value of each column, header included, without the minimum width of the columns
+func measureColumnLengths(table *Table, measurer columnMeasurer) []int {
+	maxLens := make([]int, len(table.Columns))
+
+	updateMaxColumnLengths(maxLens, table.Header(), measurer)
+	for _, row := range table.Rows {
+		updateMaxColumnLengths(maxLens, row, measurer)
 	}
 
 	return maxLens
 }
 
-// Get the indices of columns that are excluded in config
-func getIndicesOfExcludedColumns(excludedColumns []string, headerLine []string) []int {
-	var excludedColumnsIndices []int
-	if len(excludedColumns) > 0 {
-		for colIdx := range len(headerLine) {
-			// if column is found in the csv and not duplicated
-			if slices.Contains(excludedColumns, headerLine[colIdx]) {
-				excludedColumnsIndices = append(excludedColumnsIndices, colIdx)
-			}
+// Grow the max length of each column to fit the given fields
+func updateMaxColumnLengths(maxLens []int, fields []string, measurer columnMeasurer) {
+	for fieldIdx, fieldVal := range fields {
+		if measurer.cellWidth(fieldVal) > maxLens[fieldIdx] {
+			maxLens[fieldIdx] = measurer.cellWidth(fieldVal)
 		}
 	}
-	return excludedColumnsIndices
-}
\ No newline at end of file
+}
+
+// Make sure every column is at least as wide as the measurer requires, e.g. to hold the separator syntax
+func applyMinimumColumnLengths(maxLens []int, columns []Column, measurer columnMeasurer) {
+	for idx, colLen := range maxLens {
+		maxLens[idx] = max(colLen, measurer.minColumnWidth(columns[idx]))
+	}
+}
@@ -0,0 +1,104 @@
+package csv2mdtable
+
+import (
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Converts CSV into tables with a config that is validated once, when the converter is created.
+// A Converter is safe for concurrent use by multiple goroutines, as long as the Renderer,
+// RowFilter and SortFunction of its config are.
+type Converter struct {
+	// Validated config, never modified after NewConverter returns
+	cfg Config
+
+	// RowFilterExpression of the config, parsed once and bound to the header line of every CSV
+	rowFilter filterNode
+}
+
+// Create a Converter from the options, applied in order on top of the zero Config.
+// Returns a *ConfigError if the options make up an invalid config.
+func NewConverter(opts ...Option) (*Converter, error) {
+	var cfg Config
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	rowFilter, err := validateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the caller keeps the slices and maps handed to the options, the converter gets its own copies
+	return &Converter{cfg: cloneConfig(cfg), rowFilter: rowFilter}, nil
+}
+
+// Get the config of the converter. Modifying it does not affect the converter.
+func (c *Converter) Config() Config {
+	return cloneConfig(c.cfg)
+}
+
+// Convert CSV string into a markdown table, see Convert
+func (c *Converter) Convert(csv string) (string, error) {
+	table, _, err := c.ConvertWithReport(csv)
+	return table, err
+}
+
+// Convert CSV string into a markdown table, see ConvertWithReport
+func (c *Converter) ConvertWithReport(csv string) (string, Report, error) {
+	if csv == "" {
+		return "", Report{}, ErrEmptyInput
+	}
+
+	report := &Report{}
+	table, err := convertRecords(newRecordReader(c.cfg, strings.NewReader(csv), report), c.cfg, c.rowFilter, report)
+	return table, *report, err
+}
+
+// Convert CSV data read from r into a markdown table written to w, see ConvertReader
+func (c *Converter) ConvertReader(r io.Reader, w io.Writer) error {
+	_, err := c.ConvertReaderWithReport(r, w)
+	return err
+}
+
+// Convert CSV data read from r into a markdown table written to w, see ConvertReaderWithReport
+func (c *Converter) ConvertReaderWithReport(r io.Reader, w io.Writer) (Report, error) {
+	report := &Report{}
+	err := convertReader(r, w, c.cfg, c.rowFilter, report)
+	return *report, err
+}
+
+// Convert records that were already split into fields, e.g. read with encoding/csv or built in memory,
+// into a markdown table. The first record is the header line, unless the config says otherwise (see HasHeader).
+// Records with another number of fields than the header line are handled according to RaggedRows.
+// The records are not modified.
+func (c *Converter) ConvertRecords(records [][]string) (string, error) {
+	table, _, err := c.ConvertRecordsWithReport(records)
+	return table, err
+}
+
+// Convert records that were already split into fields into a markdown table, like ConvertRecords.
+// Also returns the report of the records that were repaired in lenient mode, see ConvertWithReport.
+func (c *Converter) ConvertRecordsWithReport(records [][]string) (string, Report, error) {
+	report := &Report{}
+	table, err := convertRecords(newRecordReaderFrom(c.cfg, &sliceRecordSource{records: records}, report), c.cfg, c.rowFilter, report)
+	return table, *report, err
+}
+
+// Copy the slices and maps of the config, so the copy can be modified without affecting the original
+func cloneConfig(cfg Config) Config {
+	cfg.ColumnAlign = maps.Clone(cfg.ColumnAlign)
+	cfg.ColumnAliases = maps.Clone(cfg.ColumnAliases)
+	cfg.ColumnIndexAlign = maps.Clone(cfg.ColumnIndexAlign)
+	cfg.ColumnOrder = slices.Clone(cfg.ColumnOrder)
+	cfg.ExcludedColumnIndices = slices.Clone(cfg.ExcludedColumnIndices)
+	cfg.ExcludedColumns = slices.Clone(cfg.ExcludedColumns)
+	cfg.HeaderNames = slices.Clone(cfg.HeaderNames)
+	cfg.IncludedColumns = slices.Clone(cfg.IncludedColumns)
+	cfg.SortRows = slices.Clone(cfg.SortRows)
+
+	return cfg
+}
@@ -2,27 +2,211 @@ package csv2mdtable
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
 	"strings"
 )
 
-func createCSVReader(cfg Config, csvString string) *csv.Reader {
-	r := csv.NewReader(strings.NewReader(csvString))
+func createCSVReader(cfg Config, r io.Reader) *csv.Reader {
+	csvReader := csv.NewReader(r)
 
 	if cfg.CSVReaderConfig.Comma != 0 {
-		r.Comma = cfg.CSVReaderConfig.Comma
+		csvReader.Comma = cfg.CSVReaderConfig.Comma
 	}
 
 	if cfg.CSVReaderConfig.Comment != 0 {
-		r.Comment = cfg.CSVReaderConfig.Comment
+		csvReader.Comment = cfg.CSVReaderConfig.Comment
 	}
 
-	if cfg.CSVReaderConfig.FieldsPerRecord > 0 {
-		r.FieldsPerRecord = cfg.CSVReaderConfig.FieldsPerRecord
+	// the number of fields is checked by recordReader, see RaggedRows
+	csvReader.FieldsPerRecord = -1
+
+	csvReader.LazyQuotes = cfg.CSVReaderConfig.LazyQuotes
+	csvReader.ReuseRecord = cfg.CSVReaderConfig.ReuseRecord
+	csvReader.TrimLeadingSpace = cfg.CSVReaderConfig.TrimLeadingSpace
+
+	return csvReader
+}
+
+// Reads the records of the CSV and fits them to the number of fields of the table, see RaggedRows.
+// In lenient mode (see Config.Lenient), malformed records are repaired or skipped and reported as
+// warnings instead of failing the conversion.
+// The first record of a CSV without a header line (see HasHeader) is read to generate the header line
+// and handed out again as the first data row.
+type recordReader struct {
+	source recordSource
+
+	// record to return from the next Read, if not nil
+	pending []string
+
+	// line of the last record read, starting at 1
+	line int
+
+	// number of fields every record should have, 0 until the first record is read (see FieldsPerRecord)
+	fieldCount int
+
+	raggedRows    RaggedRowPolicy
+	raggedRowFill string
+
+	lenient     bool
+	maxWarnings int
+
+	// collects the warnings of lenient mode
+	report *Report
+}
+
+// Source of the records of a recordReader, usually a *csv.Reader
+type recordSource interface {
+	Read() ([]string, error)
+
+	// Get the line and column of a field of the last record read, starting at 1
+	FieldPos(field int) (line int, column int)
+}
+
+// Hands out records that were already split into fields, see Converter.ConvertRecords
+type sliceRecordSource struct {
+	records [][]string
+
+	// index of the next record to read
+	next int
+}
+
+func newRecordReader(cfg Config, r io.Reader, report *Report) *recordReader {
+	return newRecordReaderFrom(cfg, createCSVReader(cfg, r), report)
+}
+
+func newRecordReaderFrom(cfg Config, source recordSource, report *Report) *recordReader {
+	return &recordReader{
+		source:        source,
+		fieldCount:    max(cfg.CSVReaderConfig.FieldsPerRecord, 0),
+		raggedRows:    cfg.RaggedRows,
+		raggedRowFill: cfg.RaggedRowFill,
+		lenient:       cfg.Lenient,
+		maxWarnings:   cfg.MaxWarnings,
+		report:        report,
+	}
+}
+
+// Read the next record. Returns io.EOF once the input is exhausted and a *ParseError if a record cannot be read.
+func (r *recordReader) Read() ([]string, error) {
+	if record := r.pending; record != nil {
+		r.pending = nil
+		return record, nil
+	}
+
+	for {
+		record, err := r.source.Read()
+
+		if err == io.EOF {
+			return nil, err
+		}
+
+		if err == nil {
+			r.line, _ = r.source.FieldPos(0)
+			record, err = r.fitFields(record)
+		}
+
+		if err == nil {
+			return record, nil
+		}
+
+		var csvErr *csv.ParseError
+		if !r.lenient || !errors.As(err, &csvErr) {
+			return nil, newParseError(err)
+		}
+
+		warning := Warning{Line: csvErr.StartLine, Err: csvErr, Skipped: true}
+
+		if errors.Is(err, csv.ErrFieldCount) {
+			record = fitRecord(record, r.fieldCount)
+			warning.Skipped = false
+		}
+
+		r.report.Warnings = append(r.report.Warnings, warning)
+
+		if r.maxWarnings > 0 && len(r.report.Warnings) > r.maxWarnings {
+			return nil, fmt.Errorf("%w, giving up after %d: %w", ErrTooManyWarnings, r.maxWarnings, newParseError(err))
+		}
+
+		if !warning.Skipped {
+			return record, nil
+		}
+	}
+}
+
+// Fit a record with another number of fields than the first record (or FieldsPerRecord) according to
+// the ragged row policy. Returns the record along with a *csv.ParseError if it cannot be fit.
+// Records read with OverflowRaggedRows get one more field, holding the extra values of long records.
+func (r *recordReader) fitFields(record []string) ([]string, error) {
+	if r.fieldCount == 0 {
+		r.fieldCount = len(record)
+	}
+
+	switch {
+	case len(record) < r.fieldCount && r.raggedRows != RaggedRowsError:
+		for len(record) < r.fieldCount {
+			record = append(record, r.raggedRowFill)
+		}
+	case len(record) > r.fieldCount && r.raggedRows == TruncateRaggedRows:
+		record = record[:r.fieldCount]
+	case len(record) > r.fieldCount && r.raggedRows == OverflowRaggedRows:
+		overflow := strings.Join(record[r.fieldCount:], mergedValuesSeparator)
+		return append(record[:r.fieldCount:r.fieldCount], overflow), nil
+	case len(record) != r.fieldCount:
+		line, _ := r.source.FieldPos(0)
+		return record, &csv.ParseError{StartLine: line, Line: line, Column: 1, Err: csv.ErrFieldCount}
+	}
+
+	if r.raggedRows == OverflowRaggedRows {
+		return append(record, ""), nil
+	}
+
+	return record, nil
+}
+
+// Read every remaining record, see Read. Also returns the line of each record.
+func (r *recordReader) ReadAll() ([][]string, []int, error) {
+	var records [][]string
+	var lines []int
+
+	for {
+		record, err := r.Read()
+
+		if err == io.EOF {
+			return records, lines, nil
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the record may be reused by the CSV reader (see ReuseRecord)
+		records = append(records, slices.Clone(record))
+		lines = append(lines, r.line)
 	}
+}
+
+func (s *sliceRecordSource) Read() ([]string, error) {
+	if s.next >= len(s.records) {
+		return nil, io.EOF
+	}
+
+	s.next++
 
-	r.LazyQuotes = cfg.CSVReaderConfig.LazyQuotes
-	r.ReuseRecord = cfg.CSVReaderConfig.ReuseRecord
-	r.TrimLeadingSpace = cfg.CSVReaderConfig.TrimLeadingSpace
+	// cloned, so fitting the record to the table never writes to the records of the caller
+	return slices.Clone(s.records[s.next-1]), nil
+}
+
+// The line of a record is its position in the records, starting at 1
+func (s *sliceRecordSource) FieldPos(field int) (int, int) {
+	return s.next, field + 1
+}
 
-	return r
+// Pad the record with empty values or drop its extra values, to get the given number of fields
+func fitRecord(record []string, fieldCount int) []string {
+	fitted := make([]string, fieldCount)
+	copy(fitted, record)
+	return fitted
 }
@@ -0,0 +1,244 @@
+package csv2mdtable
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Number of unchanged lines shown around every change of a unified diff
+const diffContextLines = 3
+
+// A line of an edit script: ' ' if the line is in both texts, '-' if it was removed and '+' if it was added
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// Create a unified diff turning text from into text to. The line numbers of the hunks start at firstLine
+// for both texts, so they can refer to the lines of a larger document. Returns an empty string if the texts are equal.
+func unifiedDiff(fromName string, toName string, from string, to string, firstLine int) string {
+	edits := diffLines(splitLines(from), splitLines(to))
+
+	var result strings.Builder
+
+	for _, hunk := range diffHunks(edits) {
+		if result.Len() == 0 {
+			fmt.Fprintf(&result, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		// line numbers of the first line of the hunk in both texts
+		fromLine, toLine := firstLine, firstLine
+		for _, edit := range edits[:hunk[0]] {
+			if edit.kind != '+' {
+				fromLine++
+			}
+			if edit.kind != '-' {
+				toLine++
+			}
+		}
+
+		fromCount, toCount := 0, 0
+		for _, edit := range edits[hunk[0]:hunk[1]] {
+			if edit.kind != '+' {
+				fromCount++
+			}
+			if edit.kind != '-' {
+				toCount++
+			}
+		}
+
+		fmt.Fprintf(&result, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+
+		for _, edit := range edits[hunk[0]:hunk[1]] {
+			result.WriteByte(edit.kind)
+			result.WriteString(edit.text)
+			result.WriteByte('\n')
+		}
+	}
+
+	return result.String()
+}
+
+// Format the range of a hunk. An empty range refers to the line before it, as done by GNU diff.
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// Group the changes of an edit script into hunks with their surrounding context.
+// Each hunk is the range [start, end) of the edit script it covers.
+func diffHunks(edits []diffLine) [][2]int {
+	var hunks [][2]int
+
+	for idx, edit := range edits {
+		if edit.kind == ' ' {
+			continue
+		}
+
+		start := max(idx-diffContextLines, 0)
+		end := min(idx+diffContextLines+1, len(edits))
+
+		// merge with the previous hunk if their context overlaps or touches
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+
+	return hunks
+}
+
+// Largest number of changed lines the shortest edit script is searched for, which takes memory quadratic in the number.
+// Texts differing by more lines, e.g. a table whose columns all got wider, are reported as replaced as a whole
+// between the lines they start and end with.
+const maxDiffEditDistance = 1000
+
+// Compute the edit script turning a into b. The lines both texts start and end with are unchanged.
+func diffLines(a []string, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []diffLine
+	for _, line := range a[:prefix] {
+		edits = append(edits, diffLine{' ', line})
+	}
+
+	edits = append(edits, shortestEditScript(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, diffLine{' ', line})
+	}
+
+	return edits
+}
+
+// Compute the shortest edit script turning a into b, using Myers' algorithm. If the texts differ by more than
+// maxDiffEditDistance lines, every line of a is removed and every line of b added instead.
+func shortestEditScript(a []string, b []string) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+
+	// v[k+offset] holds the furthest x reached on diagonal k
+	v := make([]int, 2*offset+1)
+
+	// trace[d][k+d] holds v[k+offset] before step d, for the diagonals -d to d
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEditDistance {
+			return replaceLines(a, b)
+		}
+
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[k+offset] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk back from the end of both texts to find the path that was taken
+	var edits []diffLine
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, diffLine{' ', a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			edits = append(edits, diffLine{'+', b[y-1]})
+			y--
+		} else {
+			edits = append(edits, diffLine{'-', a[x-1]})
+			x--
+		}
+	}
+
+	// the path starts with the lines both texts start with
+	for x > 0 {
+		edits = append(edits, diffLine{' ', a[x-1]})
+		x--
+	}
+
+	slices.Reverse(edits)
+
+	return edits
+}
+
+// Get the edit script removing every line of a and adding every line of b
+func replaceLines(a []string, b []string) []diffLine {
+	edits := make([]diffLine, 0, len(a)+len(b))
+
+	for _, line := range a {
+		edits = append(edits, diffLine{'-', line})
+	}
+
+	for _, line := range b {
+		edits = append(edits, diffLine{'+', line})
+	}
+
+	return edits
+}
+
+// Split a text into lines, without line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	return lines
+}
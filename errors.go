@@ -0,0 +1,129 @@
+package csv2mdtable
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+)
+
+// Returned when the CSV has no records at all
+var ErrEmptyInput = errors.New("csv input is empty")
+
+// Returned by Parse when the config leaves no column in the table.
+// Convert and ConvertReader write nothing instead of failing.
+var ErrAllColumnsExcluded = errors.New("all columns were excluded from conversion")
+
+// An invalid option of the config, or an option that does not fit the CSV,
+// e.g. a column of ColumnOrder that is not in the header line
+type ConfigError struct {
+	// Name of the offending option, e.g. ColumnOrder or SortRows
+	Field string
+
+	// What is wrong with the option
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Create a ConfigError for the option, with a formatted message
+func configErrorf(field string, format string, args ...any) error {
+	return &ConfigError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// A CSV that could not be read. Err is usually a *csv.ParseError.
+type ParseError struct {
+	// Line of the input the error occurred on, starting at 1. 0 if unknown.
+	Line int
+
+	// Column (in bytes) of the line the error occurred at, starting at 1. 0 if unknown.
+	Column int
+
+	// The underlying error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "Failed to parse CSV. Error: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Create a ParseError for an error of the CSV reader, taking over its position
+func newParseError(err error) error {
+	parseErr := &ParseError{Err: err}
+
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		parseErr.Line = csvErr.Line
+		parseErr.Column = csvErr.Column
+	}
+
+	return parseErr
+}
+
+// A value that could not be parsed by a key of SortRows, e.g. a text in a column sorted numerically.
+// It is also a *ParseError, for callers handling every unreadable input alike.
+type SortError struct {
+	// Line of the input the row of the value was read from, starting at 1
+	Line int
+
+	// Column of the value in the table, starting at 1
+	Column int
+
+	// Name of the column, as written in the sort key
+	ColumnName string
+
+	// The value that could not be parsed
+	Value string
+
+	// What is wrong with the value
+	Err error
+}
+
+func (e *SortError) Error() string {
+	return fmt.Sprintf("Failed to sort rows by column %q, value on line %d: %s", e.ColumnName, e.Line, e.Err)
+}
+
+func (e *SortError) Unwrap() []error {
+	return []error{e.Err, &ParseError{Line: e.Line, Err: e.Err}}
+}
+
+// A value of the table that could not be written in the output syntax,
+// e.g. a line break in a pipe table with NewlineError
+type RenderError struct {
+	// Row of the value, starting at 1, 0 being the header line
+	Row int
+
+	// Column of the value in the table, starting at 1
+	Column int
+
+	// Name of the column
+	ColumnName string
+
+	// The value that could not be written
+	Value string
+
+	// What went wrong
+	Err error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("value in row %d column %d (%s) %s", e.Row, e.Column, e.ColumnName, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Create a RenderError for a value that could not be padded to the width of its column. colIdx starts at 0.
+func paddingError(val string, column Column, rowIdx int, colIdx int, err error) error {
+	return &RenderError{Row: rowIdx, Column: colIdx + 1, ColumnName: column.Name, Value: val, Err: fmt.Errorf("could not be padded: %w", err)}
+}
@@ -0,0 +1,55 @@
+package csv2mdtable
+
+import "strings"
+
+// Construct a border line of a grid table, e.g. +-----+---+ with - as fill character
+func constructGridBorderLine(maxLenOfCol []int, fill rune) string {
+	var line strings.Builder
+	line.WriteString("+")
+
+	for _, colLen := range maxLenOfCol {
+		// one space of padding on each side of the values
+		line.WriteString(strings.Repeat(string(fill), colLen+2) + "+")
+	}
+
+	line.WriteString("\n")
+
+	return line.String()
+}
+
+// Construct the lines of a grid table row. cells holds the lines of every value, the row is as high as its tallest value.
+func constructGridRowLines(cells [][]string, columns []Column, maxLenOfCol []int, currRowIdx int, mode WidthMode) (string, error) {
+	height := 1
+	for _, lines := range cells {
+		height = max(height, len(lines))
+	}
+
+	var rowLines strings.Builder
+
+	for lineIdx := range height {
+		rowLines.WriteString("|")
+
+		for i, lines := range cells {
+			line := ""
+			if lineIdx < len(lines) {
+				line = lines[lineIdx]
+			}
+
+			padded, err := padAligned(line, maxLenOfCol[i], columns[i].Align, mode)
+			if err != nil {
+				return "", paddingError(line, columns[i], currRowIdx, i, err)
+			}
+
+			rowLines.WriteString(" " + padded + " |")
+		}
+
+		rowLines.WriteString("\n")
+	}
+
+	return rowLines.String(), nil
+}
+
+// Split a value into its lines
+func splitCellLines(val string) []string {
+	return strings.Split(strings.ReplaceAll(val, "\r\n", "\n"), "\n")
+}
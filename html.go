@@ -0,0 +1,119 @@
+package csv2mdtable
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Renders an HTML table, with a <thead> holding the header line and a <tbody> holding the data rows.
+// The caption of the table becomes a <caption> element and values are HTML escaped.
+type HTMLRenderer struct {
+	// Align the cells with the deprecated align attribute instead of a text-align style,
+	// for targets that strip style attributes
+	AlignAttribute bool
+
+	// CSS class of the <table> element
+	TableClass string
+
+	// CSS class of the <thead> element
+	HeaderClass string
+
+	// CSS class of the <tbody> element
+	BodyClass string
+
+	// If set, every cell gets a class made of this prefix and the position of its column, starting at 1,
+	// e.g. "col-" gives the cells of the second column the class "col-2"
+	ColumnClassPrefix string
+}
+
+func (r HTMLRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r HTMLRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	head.WriteString("<table" + classAttribute(r.TableClass) + ">\n")
+
+	if table.Caption != "" {
+		head.WriteString("  <caption>" + escapeHTMLCell(table.Caption) + "</caption>\n")
+	}
+
+	head.WriteString("  <thead" + classAttribute(r.HeaderClass) + ">\n")
+	head.WriteString(r.constructRow("th", table, table.Header()))
+	head.WriteString("  </thead>\n")
+	head.WriteString("  <tbody" + classAttribute(r.BodyClass) + ">\n")
+
+	_, err := io.WriteString(w, head.String())
+	return err
+}
+
+func (r HTMLRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	_, err := io.WriteString(w, r.constructRow("td", table, row))
+	return err
+}
+
+func (r HTMLRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	_, err := io.WriteString(w, "  </tbody>\n</table>\n")
+	return err
+}
+
+// Construct a <tr> element, with a cell element of the given tag for every value
+func (r HTMLRenderer) constructRow(cellTag string, table *Table, row []string) string {
+	var line strings.Builder
+	line.WriteString("    <tr>\n")
+
+	for i, val := range row {
+		var class string
+		if r.ColumnClassPrefix != "" {
+			class = fmt.Sprintf("%s%d", r.ColumnClassPrefix, i+1)
+		}
+
+		fmt.Fprintf(&line, "      <%s%s%s>%s</%s>\n", cellTag, classAttribute(class), r.alignAttribute(table.Columns[i].Align), escapeHTMLCell(val), cellTag)
+	}
+
+	line.WriteString("    </tr>\n")
+
+	return line.String()
+}
+
+// Get the attribute aligning a cell
+func (r HTMLRenderer) alignAttribute(align Align) string {
+	name := alignName(align)
+
+	if r.AlignAttribute {
+		return fmt.Sprintf(` align="%s"`, name)
+	}
+
+	return fmt.Sprintf(` style="text-align: %s"`, name)
+}
+
+// Get the class attribute for the class, or an empty string if there is no class
+func classAttribute(class string) string {
+	if class == "" {
+		return ""
+	}
+
+	return ` class="` + html.EscapeString(class) + `"`
+}
+
+// Escape a value for an HTML element. Line breaks in the value become <br> elements.
+func escapeHTMLCell(val string) string {
+	val = html.EscapeString(val)
+	val = strings.ReplaceAll(val, "\r\n", "\n")
+	return strings.ReplaceAll(val, "\n", "<br>")
+}
+
+// Get the lowercase name of an alignment, as used by CSS
+func alignName(align Align) string {
+	switch align {
+	case Left:
+		return "left"
+	case Right:
+		return "right"
+	}
+
+	return "center"
+}
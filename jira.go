@@ -0,0 +1,125 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Renders a table in Jira (and Confluence) wiki markup, with ||header|| cells and |data| cells.
+// Wiki markup cannot align columns, the caption of the table is written in bold above it.
+type JiraWikiRenderer struct{}
+
+func (r JiraWikiRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r JiraWikiRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	if table.Caption != "" {
+		head.WriteString("*" + escapeJiraCell(table.Caption) + "*\n")
+	}
+
+	head.WriteString("||")
+	for _, val := range table.Header() {
+		head.WriteString(escapeJiraCell(val) + "||")
+	}
+	head.WriteString("\n")
+
+	_, err := io.WriteString(w, head.String())
+	return err
+}
+
+func (r JiraWikiRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	var line strings.Builder
+
+	line.WriteString("|")
+	for _, val := range row {
+		line.WriteString(escapeJiraCell(val) + "|")
+	}
+	line.WriteString("\n")
+
+	_, err := io.WriteString(w, line.String())
+	return err
+}
+
+func (r JiraWikiRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+// Escape the characters of a value that wiki markup would read as cell separators, macros, links, images or text effects.
+// Line breaks in the value become forced line breaks (\\) and empty values a single space, as || starts a header cell.
+func escapeJiraCell(val string) string {
+	if val == "" {
+		return " "
+	}
+
+	lines := splitCellLines(val)
+	for i, line := range lines {
+		lines[i] = escapeJiraLine(line)
+	}
+
+	return strings.Join(lines, `\\`)
+}
+
+// Characters escaped with a backslash wherever they appear
+const jiraSpecialChars = "|{}[]!*_+^~?#"
+
+// Escape a line of a value. Dashes are only escaped where they would start a list, a horizontal rule
+// or a -strikethrough-, so dates and negative numbers stay readable in the markup.
+func escapeJiraLine(line string) string {
+	runes := []rune(line)
+
+	var res strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '\\':
+			// a backslash escapes the next character and two of them force a line break. It is written as is
+			// before ordinary characters, like in C:\logs, and as an entity where wiki markup would combine it
+			if i+1 < len(runes) && !strings.ContainsRune(`\-`+jiraSpecialChars, runes[i+1]) {
+				res.WriteRune(r)
+			} else {
+				res.WriteString("&#92;")
+			}
+		case r == '-' && isJiraMarkupDash(runes, i):
+			res.WriteString(`\-`)
+		case strings.ContainsRune(jiraSpecialChars, r):
+			res.WriteRune('\\')
+			res.WriteRune(r)
+		default:
+			res.WriteRune(r)
+		}
+	}
+
+	return res.String()
+}
+
+// Check whether the dash at idx would be read as markup: a list item or horizontal rule at the start of the line,
+// or the opening dash of a -strikethrough-, which follows a non-word character and has a closing dash later on.
+func isJiraMarkupDash(runes []rune, idx int) bool {
+	if idx == 0 && len(runes) > 1 && (runes[1] == ' ' || runes[1] == '-') {
+		return true
+	}
+
+	if idx > 0 && isJiraWordRune(runes[idx-1]) {
+		return false
+	}
+
+	if idx+1 == len(runes) || unicode.IsSpace(runes[idx+1]) {
+		return false
+	}
+
+	// the closing dash follows a non-space character and is not followed by a word character
+	for j := idx + 2; j < len(runes); j++ {
+		if runes[j] == '-' && !unicode.IsSpace(runes[j-1]) && (j+1 == len(runes) || !isJiraWordRune(runes[j+1])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isJiraWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
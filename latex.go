@@ -0,0 +1,132 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+)
+
+// Renders a LaTeX tabular environment, with the alignment of every column in its column spec (l, c or r).
+// The table is wrapped in a table float when Float is set or the table has a caption, as \caption only works in floats.
+type LaTeXRenderer struct {
+	// Draw the rules with \toprule, \midrule and \bottomrule of the booktabs package instead of \hline
+	Booktabs bool
+
+	// Wrap the tabular environment in a centered table float
+	Float bool
+}
+
+func (r LaTeXRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r LaTeXRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	if r.isFloat(table) {
+		head.WriteString("\\begin{table}[htbp]\n")
+		head.WriteString("  \\centering\n")
+
+		if table.Caption != "" {
+			head.WriteString("  \\caption{" + escapeLaTeXCell(table.Caption) + "}\n")
+		}
+	}
+
+	indent := r.indent(table)
+
+	var columnSpec strings.Builder
+	for _, column := range table.Columns {
+		columnSpec.WriteString(latexColumnSpec(column.Align))
+	}
+
+	head.WriteString(indent + "\\begin{tabular}{" + columnSpec.String() + "}\n")
+	head.WriteString(indent + "  " + r.rule(`\toprule`) + "\n")
+	head.WriteString(indent + "  " + constructLaTeXRow(table.Header()))
+	head.WriteString(indent + "  " + r.rule(`\midrule`) + "\n")
+
+	_, err := io.WriteString(w, head.String())
+	return err
+}
+
+func (r LaTeXRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	_, err := io.WriteString(w, r.indent(table)+"  "+constructLaTeXRow(row))
+	return err
+}
+
+func (r LaTeXRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	indent := r.indent(table)
+
+	tail := indent + "  " + r.rule(`\bottomrule`) + "\n" + indent + "\\end{tabular}\n"
+	if r.isFloat(table) {
+		tail += "\\end{table}\n"
+	}
+
+	_, err := io.WriteString(w, tail)
+	return err
+}
+
+// Whether the tabular environment is wrapped in a table float
+func (r LaTeXRenderer) isFloat(table *Table) bool {
+	return r.Float || table.Caption != ""
+}
+
+// Get the indentation of the tabular environment
+func (r LaTeXRenderer) indent(table *Table) string {
+	if r.isFloat(table) {
+		return "  "
+	}
+
+	return ""
+}
+
+// Get the booktabs rule, or \hline if booktabs are not used
+func (r LaTeXRenderer) rule(booktabsRule string) string {
+	if r.Booktabs {
+		return booktabsRule
+	}
+
+	return `\hline`
+}
+
+// Construct a row of the tabular environment, ending with \\
+func constructLaTeXRow(row []string) string {
+	cells := make([]string, len(row))
+	for i, val := range row {
+		cells[i] = escapeLaTeXCell(val)
+	}
+
+	return strings.Join(cells, " & ") + ` \\` + "\n"
+}
+
+// Get the column spec of an alignment
+func latexColumnSpec(align Align) string {
+	switch align {
+	case Left:
+		return "l"
+	case Right:
+		return "r"
+	}
+
+	return "c"
+}
+
+// Escape the special characters of LaTeX. Line breaks in the value become spaces, as l, c and r columns cannot break lines.
+func escapeLaTeXCell(val string) string {
+	return strings.Join(splitCellLines(latexReplacer.Replace(val)), " ")
+}
+
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"{", `\{`,
+	"}", `\}`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+	// the default font encoding prints other characters for these
+	"<", `\textless{}`,
+	">", `\textgreater{}`,
+	"|", `\textbar{}`,
+)
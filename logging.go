@@ -0,0 +1,38 @@
+package csv2mdtable
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Get the logger the diagnostics of a conversion are written to, see Config.Logger.
+// VerboseLogging lets debug records through, without changing the level of the logger itself.
+func loggerFor(cfg Config) *slog.Logger {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	if cfg.VerboseLogging {
+		return slog.New(verboseHandler{logger.Handler()})
+	}
+
+	return logger
+}
+
+// Passes debug records on to the handler it wraps, whatever the level of that handler
+type verboseHandler struct {
+	slog.Handler
+}
+
+func (h verboseHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug
+}
+
+func (h verboseHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return verboseHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h verboseHandler) WithGroup(name string) slog.Handler {
+	return verboseHandler{h.Handler.WithGroup(name)}
+}
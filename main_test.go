@@ -1,6 +1,18 @@
 package csv2mdtable
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +24,7 @@ const STRINGS_SHOULD_BE_THE_SAME = "The two strings should be the same"
 func TestPadStart(t *testing.T) {
 	originalString := "start"
 	expected := "     start"
-	res, err := padStart(originalString, 10, ' ')
+	res, err := padStart(originalString, 10, ' ', RuneCountWidth)
 
 	assert.Nil(t, err, "padStart should not return a non-nil error")
 
@@ -22,7 +34,7 @@ func TestPadStart(t *testing.T) {
 func TestPadEnd(t *testing.T) {
 	originalString := "end"
 	expected := "end       "
-	res, err := padEnd(originalString, 10, ' ')
+	res, err := padEnd(originalString, 10, ' ', RuneCountWidth)
 
 	assert.Nil(t, err, "padEnd should not return a non-nil error")
 
@@ -32,7 +44,7 @@ func TestPadEnd(t *testing.T) {
 func TestPadCenterEven(t *testing.T) {
 	originalString := "eleven"
 	expected := "  eleven  "
-	res, err := padCenter(originalString, 10, ' ')
+	res, err := padCenter(originalString, 10, ' ', RuneCountWidth)
 
 	assert.Nil(t, err, "padCenter should not return a non-nil error")
 
@@ -42,13 +54,42 @@ func TestPadCenterEven(t *testing.T) {
 func TestPadCenterOdd(t *testing.T) {
 	originalString := "eight"
 	expected := "  eight   "
-	res, err := padCenter(originalString, 10, ' ')
+	res, err := padCenter(originalString, 10, ' ', RuneCountWidth)
 
 	assert.Nil(t, err, "padCenter should not return a non-nil error")
 
 	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
 }
 
+func TestDisplayWidth(t *testing.T) {
+	cases := map[string]int{
+		"abc":          3,
+		"日本語":          6,
+		"ｶﾀｶﾅ":         4,
+		"Ｆｕｌｌ":         8,
+		"e\u0301":      1,
+		"🇨🇱":           2,
+		"👩\u200D💻":     2,
+		"👍🏽":           2,
+		"\u2764\uFE0F": 2,
+		"\u2764":       1,
+		"한국어":          6,
+		"\t":           0,
+	}
+
+	for val, expected := range cases {
+		assert.Equal(t, expected, displayWidth(val), "Display width of "+val)
+	}
+}
+
+func TestPadCenterDisplayWidth(t *testing.T) {
+	res, err := padCenter("日本", 7, ' ', DisplayWidth)
+
+	assert.Nil(t, err, "padCenter should not return a non-nil error")
+
+	assert.Equal(t, " 日本  ", res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
 /* Conversion */
 const csvString = `First name,Last name,Email,Phone
 Jane,Smith,jane.smith@email.com,555-555-1212
@@ -187,6 +228,113 @@ func TestRightAlign(t *testing.T) {
 	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
 }
 
+const csvStringFinancial = `Name,Amount,Share,Paid,Note
+Alice,"$1,200.50",12%,yes,
+Bob,(300.00),3.5%,no,late
+Carol,42,0%,Y,`
+
+func TestColumnAlign(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.ColumnAlign = map[string]Align{"Phone": Right}
+	cfg.ColumnIndexAlign = map[int]Align{2: Center, 3: Left}
+
+	expected := `| First name | Last name |        Email         |        Phone |
+| :--------- | :-------- | :------------------: | -----------: |
+| Jane       | Smith     | jane.smith@email.com | 555-555-1212 |
+| John       | Doe       |  john.doe@email.com  | 555-555-3434 |
+| Alice      | Wonder    | alice@wonderland.com | 555-555-5656 |`
+
+	res, err := Convert(csvString, cfg)
+
+	assert.Nil(t, err, "Convert with per-column alignment should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestColumnAlignOutOfRange(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.ColumnAlign = map[string]Align{"Phone": 5}
+
+	_, err := Convert(csvString, cfg)
+
+	assert.NotNil(t, err, "Convert with out of range column alignment should return an error")
+}
+
+func TestAutoAlign(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.AutoAlign = true
+	cfg.ColumnAlign = map[string]Align{"Name": Right}
+
+	expected := `|  Name |    Amount | Share | Paid | Note |
+| ----: | --------: | ----: | :--: | :--- |
+| Alice | $1,200.50 |   12% | yes  |      |
+|   Bob |  (300.00) |  3.5% |  no  | late |
+| Carol |        42 |    0% |  Y   |      |`
+
+	res, err := Convert(csvStringFinancial, cfg)
+
+	assert.Nil(t, err, "Convert with auto alignment should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+
+	// a text column is aligned left
+	cfg.ColumnAlign = nil
+	table, err := Parse(csvStringFinancial, cfg)
+
+	assert.Nil(t, err, "Parse with auto alignment should not return a non-nil error")
+
+	assert.Equal(t, Left, table.Columns[0].Align, "Text column should be aligned left")
+}
+
+func TestAutoAlignCompactStreaming(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.AutoAlign = true
+	cfg.Compact = true
+
+	expected := `|Name|Amount|Share|Paid|Note|
+|:-|-:|-:|:-:|:-|
+|Alice|$1,200.50|12%|yes||
+|Bob|(300.00)|3.5%|no|late|
+|Carol|42|0%|Y||
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(io.MultiReader(strings.NewReader(csvStringFinancial)), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with auto alignment should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestIsNumericValue(t *testing.T) {
+	for _, val := range []string{"42", "-3.14", "+7", "1,234.50", "$12", "12 €", "(1,000.00)", "15%", "-$5", "1e6"} {
+		assert.True(t, isNumericValue(val), val+" should be numeric")
+	}
+
+	for _, val := range []string{"NaN", "Inf", "0x10", "12 apples", "$", "%", "555-555-1212", "v1.2"} {
+		assert.False(t, isNumericValue(val), val+" should not be numeric")
+	}
+}
+
+func TestConvertDisplayWidth(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.WidthMode = DisplayWidth
+
+	expected := "| Name       | Country |\n" +
+		"| :--------- | :------ |\n" +
+		"| 山田太郎   | 🇯🇵      |\n" +
+		"| Jose\u0301 Ñúñez | 🇨🇱      |\n" +
+		"| Zoë        | 🇳🇴      |"
+
+	res, err := Convert("Name,Country\n山田太郎,🇯🇵\nJose\u0301 Ñúñez,🇨🇱\nZoë,🇳🇴", cfg)
+
+	assert.Nil(t, err, "Convert with display width should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
 /* CSV READER CONFIG OPTIONS */
 func TestWithCustomDelimiter(t *testing.T) {
 	cfg := createGenericConfig()
@@ -494,6 +642,1683 @@ func TestEscapePipeCharacter(t *testing.T) {
 	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
 }
 
+/* STREAMING */
+func TestConvertReaderSeekable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+
+	expected, err := Convert(csvStringWithPipeCharacters, cfg)
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+
+	var out bytes.Buffer
+	err = ConvertReader(strings.NewReader(csvStringWithPipeCharacters), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader should not return a non-nil error")
+
+	assert.Equal(t, expected+"\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderSpooled(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.SortColumns = Descending
+	cfg.ExcludedColumns = []string{"Email"}
+
+	expected, err := Convert(csvString, cfg)
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+
+	// io.MultiReader hides the Seek method so the records have to be spooled
+	var out bytes.Buffer
+	err = ConvertReader(io.MultiReader(strings.NewReader(csvString)), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with a non-seekable reader should not return a non-nil error")
+
+	assert.Equal(t, expected+"\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderSampledWidths(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.StreamWidthSampleRows = 1
+
+	expected := `| #  | first name | last name | email                       | gender |
+| :- | :--------- | :-------- | :-------------------------- | :----- |
+| 1  | Herman     | Gribbin   | hgribbin0@deliciousdays.com | Male   |
+| 2  | Bing       | Langthorne | blangthorne1@a8.net         | Male   |
+| 3  | Keith      | Hansford  | khansford2@reference.com    | Male   |
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader(csvStringWithNarrowColumn), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with sampled widths should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderCompact(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.Caption = "Compact"
+
+	expected, err := Convert(csvString, cfg)
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+
+	var out bytes.Buffer
+	err = ConvertReader(io.MultiReader(strings.NewReader(csvString)), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader compact should not return a non-nil error")
+
+	assert.Equal(t, expected+"\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderEmptyInput(t *testing.T) {
+	cfg := createGenericConfig()
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader(""), &out, cfg)
+
+	assert.NotNil(t, err, "ConvertReader with empty input should return an error")
+}
+
+func TestConvertFile(t *testing.T) {
+	cfg := createGenericConfig()
+
+	path := filepath.Join(t.TempDir(), "contacts.csv")
+	assert.Nil(t, os.WriteFile(path, []byte(csvString), 0o644), "Writing the CSV file should not fail")
+
+	expected, err := Convert(csvString, cfg)
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+
+	var out bytes.Buffer
+	err = ConvertFile(path, &out, cfg)
+
+	assert.Nil(t, err, "ConvertFile should not return a non-nil error")
+
+	assert.Equal(t, expected+"\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* TABLE AND RENDERERS */
+func TestParse(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Contacts"
+	cfg.ExcludedColumns = []string{"Email"}
+	cfg.SortColumns = Descending
+
+	table, err := Parse(csvString, cfg)
+
+	assert.Nil(t, err, "Parse should not return a non-nil error")
+
+	assert.Equal(t, "Contacts", table.Caption, STRINGS_SHOULD_BE_THE_SAME)
+	assert.Equal(t, []Column{
+		{Name: "Phone", Align: Center, Width: 12},
+		{Name: "Last name", Align: Center, Width: 9},
+		{Name: "First name", Align: Center, Width: 10},
+	}, table.Columns, "Columns should be excluded and sorted")
+	assert.Equal(t, [][]string{
+		{"555-555-1212", "Smith", "Jane"},
+		{"555-555-3434", "Doe", "John"},
+		{"555-555-5656", "Wonder", "Alice"},
+	}, table.Rows, "Rows should follow the order of the columns")
+}
+
+func TestParseKeepsPipeCharacters(t *testing.T) {
+	table, err := Parse(csvStringWithPipeCharacters, createGenericConfig())
+
+	assert.Nil(t, err, "Parse should not return a non-nil error")
+
+	assert.Equal(t, "A || B", table.Rows[0][1], "Values should not be escaped before rendering")
+}
+
+// Renders the header line as a comma separated list, used to test custom renderers
+type headerRenderer struct{}
+
+func (headerRenderer) Render(w io.Writer, table *Table) error {
+	_, err := io.WriteString(w, strings.Join(table.Header(), ",")+"\n")
+	return err
+}
+
+func TestConvertWithCustomRenderer(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Renderer = headerRenderer{}
+	cfg.SortColumns = Ascending
+
+	res, err := Convert(csvString, cfg)
+
+	assert.Nil(t, err, "Convert with custom renderer should not return a non-nil error")
+
+	assert.Equal(t, "Email,First name,Last name,Phone", res, STRINGS_SHOULD_BE_THE_SAME)
+
+	var out bytes.Buffer
+	err = ConvertReader(strings.NewReader(csvString), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with custom renderer should not return a non-nil error")
+
+	assert.Equal(t, "Email,First name,Last name,Phone\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* MARKDOWN DOCUMENTS */
+const markdownDocument = "# Prices\r\n\r\nIntro text.\r\n<!-- csv2md src=\"data/prices.csv\" align=left caption='Price list' -->\r\n| stale |\r\n<!-- /csv2md -->\r\n\r\n```md\r\n<!-- csv2md src=\"missing.csv\" -->\r\n```\r\n<!-- csv2md src=data/prices.csv compact exclude=Price -->\r\n<!-- /csv2md -->\r\nOutro"
+
+func TestUpdateMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "data"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "data", "prices.csv"), []byte("Item,Price\nApple,1.20\nPear,0.90"), 0o644))
+
+	expected := "# Prices\r\n\r\nIntro text.\r\n<!-- csv2md src=\"data/prices.csv\" align=left caption='Price list' -->\r\n" +
+		"<!-- Price list -->\r\n| Item  | Price |\r\n| :---- | :---- |\r\n| Apple | 1.20  |\r\n| Pear  | 0.90  |\r\n" +
+		"<!-- /csv2md -->\r\n\r\n```md\r\n<!-- csv2md src=\"missing.csv\" -->\r\n```\r\n<!-- csv2md src=data/prices.csv compact exclude=Price -->\r\n" +
+		"|Item|\r\n|:-:|\r\n|Apple|\r\n|Pear|\r\n<!-- /csv2md -->\r\nOutro"
+
+	res, err := UpdateMarkdown([]byte(markdownDocument), dir, createGenericConfig())
+
+	assert.Nil(t, err, "UpdateMarkdown should not return a non-nil error")
+
+	assert.Equal(t, expected, string(res), STRINGS_SHOULD_BE_THE_SAME)
+
+	// a second update does not change anything
+	path := filepath.Join(dir, "readme.md")
+	assert.Nil(t, os.WriteFile(path, res, 0o644))
+
+	changed, err := UpdateMarkdownFile(path, createGenericConfig())
+
+	assert.Nil(t, err, "UpdateMarkdownFile should not return a non-nil error")
+
+	assert.False(t, changed, "An up to date document should not be rewritten")
+}
+
+func TestUpdateMarkdownSourceOutsideDirectory(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "docs")
+	assert.Nil(t, os.Mkdir(dir, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(parent, "secret.csv"), []byte("Key\nhunter2"), 0o644))
+
+	for _, src := range []string{"../secret.csv", filepath.ToSlash(filepath.Join(parent, "secret.csv"))} {
+		doc := "<!-- csv2md src=\"" + src + "\" -->\n<!-- /csv2md -->\n"
+
+		_, err := UpdateMarkdown([]byte(doc), dir, createGenericConfig())
+
+		assert.ErrorContains(t, err, `line 1: source "`+src+`" is not a relative path inside the directory of the document`)
+	}
+
+	// symbolic links cannot lead out of the directory either
+	if err := os.Symlink(filepath.Join(parent, "secret.csv"), filepath.Join(dir, "link.csv")); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+
+	_, err := UpdateMarkdown([]byte("<!-- csv2md src=link.csv -->\n<!-- /csv2md -->\n"), dir, createGenericConfig())
+
+	assert.NotNil(t, err, "UpdateMarkdown should not read a symbolic link leading out of the directory")
+}
+
+func TestMarkerSortRows(t *testing.T) {
+	block, err := parseOpeningMarker(`src=a.csv sort-rows="When:desc:date=Jan 2, 2006;Item:nocase"`, createGenericConfig())
+
+	assert.Nil(t, err, "parseOpeningMarker should not return a non-nil error")
+
+	expected := []RowSortKey{
+		{Column: "When", Descending: true, Comparison: DateComparison, DateLayout: "Jan 2, 2006"},
+		{Column: "Item", Comparison: CaseInsensitiveComparison},
+	}
+
+	assert.Equal(t, expected, block.cfg.SortRows)
+}
+
+func TestMarkerExcludeIndex(t *testing.T) {
+	block, err := parseOpeningMarker(`src=a.csv exclude-index=0,2`, createGenericConfig())
+
+	assert.Nil(t, err, "parseOpeningMarker should not return a non-nil error")
+	assert.Equal(t, []int{0, 2}, block.cfg.ExcludedColumnIndices)
+
+	_, err = parseOpeningMarker(`src=a.csv exclude-index=first`, createGenericConfig())
+	assert.ErrorContains(t, err, `exclude-index expects comma separated column indices, got "first"`)
+}
+
+func TestUpdateMarkdownMalformedMarkers(t *testing.T) {
+	for _, doc := range []string{
+		"<!-- csv2md src=a.csv -->\n",
+		"<!-- /csv2md -->\n",
+		"<!-- csv2md align=left -->\n<!-- /csv2md -->\n",
+		"<!-- csv2md src=a.csv colour=blue -->\n<!-- /csv2md -->\n",
+		"<!-- csv2md src=a.csv align=diagonal -->\n<!-- /csv2md -->\n",
+		"<!-- csv2md src=a.csv -->\n<!-- csv2md src=b.csv -->\n<!-- /csv2md -->\n",
+	} {
+		_, err := UpdateMarkdown([]byte(doc), t.TempDir(), createGenericConfig())
+
+		assert.NotNil(t, err, "UpdateMarkdown should return an error for "+doc)
+	}
+}
+
+func TestCheckMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "prices.csv"), []byte("Item,Price\nApple,1.20\nPear,0.90"), 0o644))
+
+	upToDate := "<!-- csv2md src=prices.csv compact -->\n|Item|Price|\n|:-:|:-:|\n|Apple|1.20|\n|Pear|0.90|\n<!-- /csv2md -->\n"
+
+	stale, err := CheckMarkdown([]byte(upToDate), dir, createGenericConfig())
+
+	assert.Nil(t, err, "CheckMarkdown should not return a non-nil error")
+
+	assert.Empty(t, stale, "An up to date table should not be reported")
+
+	handEdited := "# Prices\n<!-- csv2md src=prices.csv compact -->\n|Item|Price|\n|:-:|:-:|\n|Apple|1.00|\n|Pear|0.90|\n<!-- /csv2md -->\n"
+	path := filepath.Join(dir, "prices.md")
+	assert.Nil(t, os.WriteFile(path, []byte(handEdited), 0o644))
+
+	expectedDiff := "--- " + path + "\n+++ " + path + " (generated from prices.csv)\n@@ -3,4 +3,4 @@\n |Item|Price|\n |:-:|:-:|\n-|Apple|1.00|\n+|Apple|1.20|\n |Pear|0.90|\n"
+
+	stale, err = CheckMarkdownFiles([]string{path}, createGenericConfig())
+
+	assert.Nil(t, err, "CheckMarkdownFiles should not return a non-nil error")
+
+	assert.Equal(t, []StaleTable{{Path: path, Line: 2, Src: "prices.csv", Diff: expectedDiff}}, stale, "The hand-edited table should be reported")
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+
+	expected := "--- from\n+++ to\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -9,3 +9,4 @@\n i\n j\n k\n+l\n"
+
+	assert.Equal(t, expected, unifiedDiff("from", "to", from, to, 1), STRINGS_SHOULD_BE_THE_SAME)
+
+	assert.Empty(t, unifiedDiff("from", "to", from, from, 1), "Equal texts should not produce a diff")
+
+	assert.Equal(t, "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+x\n+y\n", unifiedDiff("from", "to", "", "x\ny\n", 1), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestDiffLinesReplacesLargeChanges(t *testing.T) {
+	var from, to []string
+	for i := range maxDiffEditDistance {
+		from = append(from, fmt.Sprintf("| %d |", i))
+		to = append(to, fmt.Sprintf("|  %d  |", i))
+	}
+
+	edits := diffLines(slices.Concat([]string{"head"}, from, []string{"tail"}), slices.Concat([]string{"head"}, to, []string{"tail"}))
+
+	expected := []diffLine{{' ', "head"}}
+	for _, line := range from {
+		expected = append(expected, diffLine{'-', line})
+	}
+	for _, line := range to {
+		expected = append(expected, diffLine{'+', line})
+	}
+	expected = append(expected, diffLine{' ', "tail"})
+
+	assert.Equal(t, expected, edits, "Lines changed beyond the edit distance limit should be replaced as a whole")
+}
+
+/* MARKDOWN TO CSV */
+func TestParseMarkdownTableRoundTrip(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		cfg := createGenericConfig()
+		cfg.Compact = compact
+		cfg.Caption = "Pipes"
+		cfg.Align = Right
+
+		md, err := Convert(csvStringWithPipeCharacters, cfg)
+		assert.Nil(t, err, "Convert should not return a non-nil error")
+
+		table, err := ParseMarkdownTable(md)
+
+		assert.Nil(t, err, "ParseMarkdownTable should not return a non-nil error")
+
+		expected, err := Parse(csvStringWithPipeCharacters, cfg)
+		assert.Nil(t, err, "Parse should not return a non-nil error")
+
+		assert.Equal(t, expected, table, "The parsed Markdown table should match the parsed CSV")
+	}
+}
+
+func TestParseMarkdownTableRoundTripBackslashes(t *testing.T) {
+	csv := "A,B\nx\\,y\n\\|,a\\\\b\nC:\\Temp,\\_"
+
+	cfg := createGenericConfig()
+	cfg.Compact = true
+
+	md, err := Convert(csv, cfg)
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+	assert.Equal(t, "|A|B|\n|:-:|:-:|\n|x\\\\|y|\n|\\\\\\||a\\\\\\b|\n|C:\\Temp|\\_|", md, "Backslashes before a pipe, another backslash or the end of a value should be escaped")
+
+	table, err := ParseMarkdownTable(md)
+	assert.Nil(t, err, "ParseMarkdownTable should not return a non-nil error")
+
+	expected, err := Parse(csv, cfg)
+	assert.Nil(t, err, "Parse should not return a non-nil error")
+
+	assert.Equal(t, expected, table, "The parsed Markdown table should match the parsed CSV")
+
+	res, err := MarkdownToCSV("|A|B|\n|:-:|:-:|\n|x\\\\|y|", cfg)
+	assert.Nil(t, err, "MarkdownToCSV should not return a non-nil error")
+	assert.Equal(t, "A,B\nx\\,y\n", res, "An escaped backslash before a pipe should not escape the pipe")
+}
+
+func TestParseMarkdownTableWithoutOuterPipes(t *testing.T) {
+	md := `Some text
+
+Name | Amount | Note
+:--- | -----: | :--:
+Apple | 1.20
+Pear | 0.90 | ripe | extra
+
+More text`
+
+	table, err := ParseMarkdownTable(md)
+
+	assert.Nil(t, err, "ParseMarkdownTable should not return a non-nil error")
+
+	assert.Equal(t, []Column{{Name: "Name", Align: Left, Width: 5}, {Name: "Amount", Align: Right, Width: 6}, {Name: "Note", Align: Center, Width: 4}}, table.Columns, "Columns should be parsed with their alignment")
+	assert.Equal(t, [][]string{{"Apple", "1.20", ""}, {"Pear", "0.90", "ripe"}}, table.Rows, "Rows should be padded or truncated to the header")
+}
+
+func TestParseMarkdownTableNotFound(t *testing.T) {
+	_, err := ParseMarkdownTable("# Title\n\n```\n| a |\n| - |\n```\n")
+
+	assert.NotNil(t, err, "ParseMarkdownTable should return an error when there is no table outside of code blocks")
+}
+
+func TestMarkdownToCSV(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.CSVReaderConfig.Comma = ';'
+
+	md := `<!-- Contacts -->
+| Name | Company |
+| :--- | :------ |
+| Linda | Dominguez, Mcmillan and Donovan |
+| Joe | A \| B; "C" |`
+
+	expected := "Name;Company\nLinda;Dominguez, Mcmillan and Donovan\nJoe;\"A | B; \"\"C\"\"\"\n"
+
+	res, err := MarkdownToCSV(md, cfg)
+
+	assert.Nil(t, err, "MarkdownToCSV should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* REFORMAT */
+const raggedMarkdownDocument = `# Fruits
+
+Name|Price | Note
+:--|--:|---
+Apple|1.20|crisp \| sweet
+Pear|0.9
+
+- In a list:
+
+  | a | b |
+  |---|:-:|
+  | long value | x |
+
+` + "```" + `
+|not|a|
+|-|-|
+` + "```" + `
+<!-- csv2md src=fruits.csv compact -->
+|A|
+|:-:|
+<!-- /csv2md -->
+|last|
+|-|
+|row|`
+
+func TestFormatMarkdown(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+
+	expected := `# Fruits
+
+| Name  | Price | Note           |
+| :---- | :---- | :------------- |
+| Apple | 1.20  | crisp \| sweet |
+| Pear  | 0.9   |                |
+
+- In a list:
+
+  | a          | b  |
+  | :--------- | :- |
+  | long value | x  |
+
+` + "```" + `
+|not|a|
+|-|-|
+` + "```" + `
+<!-- csv2md src=fruits.csv compact -->
+|A|
+|:-:|
+<!-- /csv2md -->
+| last |
+| :--- |
+| row  |`
+
+	res, err := FormatMarkdown([]byte(raggedMarkdownDocument), cfg)
+
+	assert.Nil(t, err, "FormatMarkdown should not return a non-nil error")
+
+	assert.Equal(t, expected, string(res), STRINGS_SHOULD_BE_THE_SAME)
+
+	// formatting a formatted document does not change it
+	again, err := FormatMarkdown(res, cfg)
+
+	assert.Nil(t, err, "FormatMarkdown should not return a non-nil error")
+
+	assert.Equal(t, expected, string(again), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestFormatMarkdownPreserveAlignment(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.PreserveAlignment = true
+
+	expected := `| Name  | Price | Note |
+| :---- | ----: | :--- |
+| Apple |  1.20 | ok   |`
+
+	res, err := FormatMarkdown([]byte("Name|Price|Note\n:-|-:|-\nApple|1.20|ok"), cfg)
+
+	assert.Nil(t, err, "FormatMarkdown with preserved alignment should not return a non-nil error")
+
+	assert.Equal(t, expected, string(res), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestFormatMarkdownKeepsCommentAboveTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+
+	doc := "<!-- prettier-ignore -->\n  |a|b|\n  |-|-|\n  |1|2|\n\n<!--prettier-ignore-start-->\r\n|c|\r\n|-|\r\n<!--prettier-ignore-end-->\r\n"
+
+	expected := "<!-- prettier-ignore -->\n  | a  | b  |\n  | :- | :- |\n  | 1  | 2  |\n\n<!--prettier-ignore-start-->\r\n| c  |\r\n| :- |\r\n<!--prettier-ignore-end-->\r\n"
+
+	res, err := FormatMarkdown([]byte(doc), cfg)
+
+	assert.Nil(t, err, "FormatMarkdown should not return a non-nil error")
+
+	assert.Equal(t, expected, string(res), STRINGS_SHOULD_BE_THE_SAME)
+
+	// only comments in the form written by Convert are captions
+	table, err := ParseMarkdownTable("<!--prettier-ignore-start-->\n|c|\n|-|\n")
+
+	assert.Nil(t, err, "ParseMarkdownTable should not return a non-nil error")
+
+	assert.Empty(t, table.Caption, "A comment in another form should not become the caption")
+}
+
+/* HTML */
+func TestConvertHTML(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Q&A <draft>"
+	cfg.ExcludedColumns = []string{"ID"}
+	cfg.ColumnAlign = map[string]Align{"Description": Left}
+	cfg.Renderer = HTMLRenderer{TableClass: "data", ColumnClassPrefix: "col-"}
+
+	expected := `<table class="data">
+  <caption>Q&amp;A &lt;draft&gt;</caption>
+  <thead>
+    <tr>
+      <th class="col-1" style="text-align: center">Expression</th>
+      <th class="col-2" style="text-align: left">Description</th>
+    </tr>
+  </thead>
+  <tbody>
+    <tr>
+      <td class="col-1" style="text-align: center">a &lt; b &amp;&amp; c</td>
+      <td class="col-2" style="text-align: left">&#34;quoted&#34;<br>two lines</td>
+    </tr>
+  </tbody>
+</table>`
+
+	res, err := Convert("ID,Expression,Description\n1,a < b && c,\"\"\"quoted\"\"\ntwo lines\"", cfg)
+
+	assert.Nil(t, err, "Convert to HTML should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderHTMLAlignAttribute(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.SortColumns = Descending
+	cfg.Align = Right
+	cfg.Renderer = HTMLRenderer{AlignAttribute: true}
+
+	expected := `<table>
+  <thead>
+    <tr>
+      <th align="right">B</th>
+      <th align="right">A</th>
+    </tr>
+  </thead>
+  <tbody>
+    <tr>
+      <td align="right">2</td>
+      <td align="right">1</td>
+    </tr>
+  </tbody>
+</table>
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(io.MultiReader(strings.NewReader("A,B\n1,2")), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader to HTML should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* ASCIIDOC, RESTRUCTUREDTEXT AND ORG-MODE */
+func TestConvertAsciiDoc(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Prices"
+	cfg.Align = Left
+	cfg.ExcludedColumns = []string{"ID"}
+	cfg.ColumnAlign = map[string]Align{"Price": Right}
+	cfg.Renderer = AsciiDocRenderer{}
+
+	expected := `.Prices
+[cols="<,>",options="header"]
+|===
+|Name |Price
+|Apple \| Pear |1.20
+|Two +
+lines |3
+|===`
+
+	res, err := Convert("ID,Name,Price\n1,Apple | Pear,1.20\n2,\"Two\nlines\",3", cfg)
+
+	assert.Nil(t, err, "Convert to AsciiDoc should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertRSTGridTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Prices"
+	cfg.Align = Left
+	cfg.SortColumns = Descending
+	cfg.Renderer = RSTGridTableRenderer{}
+
+	expected := `.. table:: Prices
+
+   +-------+------------+
+   | Price | Name       |
+   +=======+============+
+   | 1.20  | \*Apple\*  |
+   +-------+------------+
+   | 3     | Two        |
+   |       | long lines |
+   +-------+------------+`
+
+	res, err := Convert("Name,Price\n*Apple*,1.20\n\"Two\nlong lines\",3", cfg)
+
+	assert.Nil(t, err, "Convert to a reST grid table should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertRSTSimpleTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.ColumnAlign = map[string]Align{"Name": Left, "Price": Right}
+	cfg.Renderer = RSTSimpleTableRenderer{}
+
+	expected := `=========  =====
+Name       Price
+=========  =====
+Apple          1
+\              2
+Two lines      3
+=========  =====`
+
+	res, err := Convert("Name,Price\nApple,1\n,2\n\"Two\nlines\",3", cfg)
+
+	assert.Nil(t, err, "Convert to a reST simple table should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderOrgTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Prices"
+	cfg.ColumnAlign = map[string]Align{"Name": Left, "Qty": Right}
+	cfg.Renderer = OrgTableRenderer{}
+
+	expected := `#+CAPTION: Prices
+| <l>              | <r> |
+| Name             | Qty |
+|------------------+-----|
+| Apple\vert{}Pear |   1 |
+| Plum             |  12 |
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(io.MultiReader(strings.NewReader("Name,Qty\nApple|Pear,1\nPlum,12")), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader to an Org-mode table should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* JIRA AND CONFLUENCE */
+func TestConvertJiraWiki(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Incidents"
+	cfg.SortColumns = Ascending
+	cfg.Renderer = JiraWikiRenderer{}
+
+	expected := `*Incidents*
+||Link||Status||Summary||
+|\[PROJ-1\]| |Disk \{full\} on a\|b\\see C:\logs|`
+
+	res, err := Convert("Summary,Status,Link\n\"Disk {full} on a|b\nsee C:\\logs\",,[PROJ-1]", cfg)
+
+	assert.Nil(t, err, "Convert to Jira wiki markup should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestEscapeJiraCell(t *testing.T) {
+	for val, expected := range map[string]string{
+		"2024-06-30":     "2024-06-30",
+		"-42.5":          "-42.5",
+		"-3 to -1":       "-3 to -1",
+		"10 - 5 = 5":     "10 - 5 = 5",
+		"x-ray-vision":   "x-ray-vision",
+		"-struck-":       `\-struck-`,
+		"was -not- done": `was \-not- done`,
+		"- item":         `\- item`,
+		"----":           `\-\---`,
+		`C:\logs`:        `C:\logs`,
+		`a\\b`:           `a&#92;\b`,
+		`ends with \`:    `ends with &#92;`,
+		`\*bold\*`:       `&#92;\*bold&#92;\*`,
+	} {
+		assert.Equal(t, expected, escapeJiraCell(val), "Escaping "+val)
+	}
+}
+
+func TestConvertConfluenceStorage(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Right
+	cfg.ExcludedColumns = []string{"ID"}
+	cfg.Renderer = ConfluenceStorageRenderer{}
+
+	expected := `<table>
+  <tbody>
+    <tr>
+      <th><p style="text-align: right;">Summary</p></th>
+    </tr>
+    <tr>
+      <td><p style="text-align: right;">a &lt; b<br />{code}</p></td>
+    </tr>
+  </tbody>
+</table>`
+
+	res, err := Convert("ID,Summary\n1,\"a < b\n{code}\"", cfg)
+
+	assert.Nil(t, err, "Convert to the Confluence storage format should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* LATEX */
+func TestConvertLaTeXBooktabs(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Results (n = 10)"
+	cfg.ColumnAlign = map[string]Align{"Model": Left, "Score": Right}
+	cfg.Renderer = LaTeXRenderer{Booktabs: true}
+
+	expected := `\begin{table}[htbp]
+  \centering
+  \caption{Results (n = 10)}
+  \begin{tabular}{lr}
+    \toprule
+    Model & Score \\
+    \midrule
+    base\_v1 & 50\% \\
+    R\&D \{x\textasciicircum{}2\} & \textasciitilde{}\$3 \\
+    \bottomrule
+  \end{tabular}
+\end{table}`
+
+	res, err := Convert("Model,Score\nbase_v1,50%\nR&D {x^2},~$3", cfg)
+
+	assert.Nil(t, err, "Convert to LaTeX should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertLaTeXTabular(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.ExcludedColumns = []string{"B"}
+	cfg.Renderer = LaTeXRenderer{}
+
+	expected := `\begin{tabular}{cc}
+  \hline
+  A & C \\
+  \hline
+  C:\textbackslash{}tmp & \#1 two lines \\
+  \hline
+\end{tabular}`
+
+	res, err := Convert("A,B,C\nC:\\tmp,x,\"#1 two\nlines\"", cfg)
+
+	assert.Nil(t, err, "Convert to LaTeX should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* TERMINAL */
+func TestConvertTerminalTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Fruit"
+	cfg.Align = Left
+	cfg.ColumnAlign = map[string]Align{"Qty": Right}
+	cfg.Renderer = TerminalTableRenderer{MaxWidth: 30, HeaderStyle: "1"}
+
+	expected := "Fruit\n" +
+		"┌───────┬─────┬──────────────┐\n" +
+		"│ \x1b[1mName \x1b[0m │ \x1b[1mQty\x1b[0m │ \x1b[1mNote        \x1b[0m │\n" +
+		"├───────┼─────┼──────────────┤\n" +
+		"│ Apple │   1 │ a rather     │\n" +
+		"│       │     │ long note    │\n" +
+		"│       │     │ about apples │\n" +
+		"│ Plum  │  12 │ two          │\n" +
+		"│       │     │ lines        │\n" +
+		"└───────┴─────┴──────────────┘"
+
+	res, err := Convert("Name,Qty,Note\nApple,1,a rather long note about apples\nPlum,12,\"two\nlines\"", cfg)
+
+	assert.Nil(t, err, "Convert to a terminal table should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderASCIITableTruncated(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.Renderer = TerminalTableRenderer{Style: ASCIIBoxStyle, MaxWidth: 20, Overflow: TruncateCells}
+
+	expected := `+------+-----------+
+| Name | Note      |
++------+-----------+
+| Plum | a long... |
+| Fig  | ? short   |
++------+-----------+
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Name,Note\nPlum,a long note\nFig,\x1b short"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader to an ASCII table should not return a non-nil error")
+
+	// the escape character is replaced, so that it cannot be interpreted by the terminal
+	assert.Equal(t, strings.ReplaceAll(expected, "?", "\uFFFD"), out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestWrapToWidth(t *testing.T) {
+	assert.Equal(t, []string{"short"}, wrapToWidth("short", 10, RuneCountWidth), "Values that fit should not be wrapped")
+	assert.Equal(t, []string{"a b", "cdefg", "hij"}, wrapToWidth("a b cdefghij", 5, RuneCountWidth), "Long words should be broken")
+	assert.Equal(t, []string{"日本", "語"}, wrapToWidth("日本語", 4, DisplayWidth), "Wide characters should be measured by display width")
+}
+
+/* LINE BREAKS AND PANDOC */
+func TestConvertNewlineToBreak(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+
+	expected := `| Name | Address             |
+| :--- | :------------------ |
+| Jane | 1 Main St<br>Boston |`
+
+	res, err := Convert("Name,Address\nJane,\"1 Main St\r\nBoston\"", cfg)
+
+	assert.Nil(t, err, "Convert with line breaks should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertNewlineToSpace(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.NewlinePolicy = NewlineToSpace
+
+	expected := `|Name|Address|
+|:-:|:-:|
+|Jane|1 Main St Boston|`
+
+	res, err := Convert("Name,Address\nJane,\"1 Main St\nBoston\"", cfg)
+
+	assert.Nil(t, err, "Convert with line breaks should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertNewlineError(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.NewlinePolicy = NewlineError
+
+	_, err := Convert("Name,Address\nJohn,Home\nJane,\"1 Main St\nBoston\"", cfg)
+
+	assert.EqualError(t, err, "value in row 2 column 2 (Address) contains a line break, which cannot be written in a pipe table")
+}
+
+func TestConvertPandocGridTable(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Caption = "Tasks"
+	cfg.ColumnAlign = map[string]Align{"Task": Left, "Hours": Right}
+	cfg.Renderer = PandocGridTableRenderer{}
+
+	expected := `Table: Tasks
+
++----------+-------+------+
+| Task     | Hours | Done |
++:=========+======:+:====:+
+| - write  |     3 | yes  |
+| - review |       |      |
++----------+-------+------+
+| Deploy   |   0.5 |  no  |
++----------+-------+------+`
+
+	res, err := Convert("Task,Hours,Done\n\"- write\n- review\",3,yes\nDeploy,0.5,no", cfg)
+
+	assert.Nil(t, err, "Convert to a Pandoc grid table should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+/* COLUMN SELECTION */
+func TestConvertIncludedColumnsOrderAndAliases(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.SortColumns = Ascending
+	cfg.IncludedColumns = []string{"name", "cust_id", "email", "phone"}
+	cfg.ExcludedColumns = []string{"phone"}
+	cfg.ColumnOrder = []string{"cust_id"}
+	cfg.ColumnAliases = map[string]string{"cust_id": "Customer ID", "email": "E-mail"}
+	cfg.ColumnAlign = map[string]Align{"cust_id": Right}
+
+	expected := `| Customer ID | E-mail        | name |
+| ----------: | :------------ | :--- |
+|          42 | jane@mail.com | Jane |`
+
+	res, err := Convert("name,phone,email,cust_id,city\nJane,555,jane@mail.com,42,Boston", cfg)
+
+	assert.Nil(t, err, "Convert with included, ordered and renamed columns should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertUnknownColumnNames(t *testing.T) {
+	csv := "name,email\nJane,jane@mail.com"
+
+	cfg := createGenericConfig()
+	cfg.IncludedColumns = []string{"name", "phone"}
+	_, err := Convert(csv, cfg)
+	assert.ErrorContains(t, err, `column "phone" of IncludedColumns does not exist in the header line`)
+
+	cfg = createGenericConfig()
+	cfg.ColumnOrder = []string{"Email"}
+	_, err = Convert(csv, cfg)
+	assert.ErrorContains(t, err, `column "Email" of ColumnOrder does not exist in the header line`)
+
+	cfg = createGenericConfig()
+	cfg.ColumnAliases = map[string]string{"mail": "E-mail"}
+	err = ConvertReader(strings.NewReader(csv), io.Discard, cfg)
+	assert.ErrorContains(t, err, `column "mail" of ColumnAliases does not exist in the header line`)
+
+	cfg = createGenericConfig()
+	cfg.ColumnOrder = []string{"name", "name"}
+	assert.EqualError(t, ValidateConfig(cfg), "column name is listed more than once in ColumnOrder")
+}
+
+/* DUPLICATE HEADERS */
+func TestConvertDuplicateHeadersSorted(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.SortColumns = Descending
+	cfg.ExcludedColumns = []string{"Notes#3"}
+
+	expected := `|Notes|Notes|Name|
+|:-:|:-:|:-:|
+|first|second|Jane|`
+
+	res, err := Convert("Notes,Name,Notes,Notes\nfirst,Jane,second,third", cfg)
+
+	assert.Nil(t, err, "Convert with duplicate headers should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertDuplicateHeaderPolicies(t *testing.T) {
+	csv := "Notes,Name,Notes_2,Notes\nfirst,Jane,x,\n,John,y,second"
+
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.DuplicateHeaders = SuffixDuplicateHeaders
+	cfg.ExcludedColumnIndices = []int{1}
+
+	res, err := Convert(csv, cfg)
+	assert.Nil(t, err, "Convert with suffixed duplicate headers should not return a non-nil error")
+	assert.Equal(t, "|Notes|Notes_2|Notes_3|\n|:-:|:-:|:-:|\n|first|x||\n||y|second|", res, STRINGS_SHOULD_BE_THE_SAME)
+
+	cfg = createGenericConfig()
+	cfg.Compact = true
+	cfg.DuplicateHeaders = MergeDuplicateHeaders
+
+	res, err = Convert("Notes,Name,Notes\nfirst,Jane,second\n,John,only", cfg)
+	assert.Nil(t, err, "Convert with merged duplicate headers should not return a non-nil error")
+	assert.Equal(t, "|Notes|Name|\n|:-:|:-:|\n|first; second|Jane|\n|only|John|", res, STRINGS_SHOULD_BE_THE_SAME)
+
+	cfg = createGenericConfig()
+	cfg.DuplicateHeaders = ErrorOnDuplicateHeaders
+
+	_, err = Convert(csv, cfg)
+	assert.ErrorContains(t, err, `columns 0 and 3 share the header name "Notes"`)
+}
+
+/* ROW SORTING */
+func TestConvertSortRowsByNumberThenName(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.ExcludedColumns = []string{"Note"}
+	cfg.SortRows = []RowSortKey{
+		{Column: "Amount", Comparison: NumericComparison, Descending: true},
+		{Column: "Name", Comparison: CaseInsensitiveComparison},
+	}
+
+	expected := `|Name|Amount|
+|:-:|:-:|
+|bob|$1,200.00|
+|alice|12|
+|Carol|12|
+|dave|(3.50)|
+|erin||`
+
+	res, err := Convert("Name,Amount,Note\nCarol,12,a\nerin,,b\ndave,(3.50),c\nbob,\"$1,200.00\",d\nalice,12,e", cfg)
+
+	assert.Nil(t, err, "Convert with sorted rows should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderSortRowsByDate(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.SortRows = []RowSortKey{{Column: "Date", Comparison: DateComparison, DateLayout: "02/01/2006"}}
+
+	expected := `|Date|Event|
+|:-:|:-:|
+|28/02/2023|b|
+|01/03/2023|a|
+|15/01/2024|c|
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Date,Event\n01/03/2023,a\n28/02/2023,b\n15/01/2024,c"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with sorted rows should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertSortRowsErrors(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.SortRows = []RowSortKey{{Column: "Amount", Comparison: NumericComparison}}
+
+	_, err := Convert("Name,Amount\nJane,12\nJohn,twelve", cfg)
+	assert.EqualError(t, err, `Failed to sort rows by column "Amount", value on line 3: "twelve" is not a number`)
+
+	var sortErr *SortError
+	assert.ErrorAs(t, err, &sortErr)
+	assert.Equal(t, SortError{Line: 3, Column: 2, ColumnName: "Amount", Value: "twelve", Err: sortErr.Err}, *sortErr)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "A value that cannot be sorted should be reported as a ParseError")
+	assert.Equal(t, 3, parseErr.Line)
+
+	// the line is the line of the CSV, not the row of the table after filtering
+	cfg.RowFilterExpression = `Name != "Jane"`
+
+	var out bytes.Buffer
+	err = ConvertReader(strings.NewReader("Name,Amount\nJane,12\nMary,7\nJohn,twelve"), &out, cfg)
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 4, parseErr.Line)
+
+	cfg.RowFilterExpression = ""
+
+	cfg.SortRows = []RowSortKey{{Column: "Amount"}}
+	cfg.ExcludedColumns = []string{"Amount"}
+
+	_, err = Convert("Name,Amount\nJane,12", cfg)
+	assert.EqualError(t, err, `column "Amount" of SortRows is not part of the table`)
+}
+
+func TestCompareNatural(t *testing.T) {
+	values := []string{"file10.txt", "file2.txt", "file02.txt", "file1", "file"}
+
+	slices.SortStableFunc(values, compareNatural)
+
+	assert.Equal(t, []string{"file", "file1", "file2.txt", "file02.txt", "file10.txt"}, values, "Runs of digits should be compared by value")
+}
+
+func TestParseRowSortKey(t *testing.T) {
+	key, err := ParseRowSortKey("Due: date:desc:date=02/01/2006")
+
+	assert.Nil(t, err, "ParseRowSortKey should not return a non-nil error")
+	assert.Equal(t, RowSortKey{Column: "Due: date", Descending: true, Comparison: DateComparison, DateLayout: "02/01/2006"}, key)
+
+	key, err = ParseRowSortKey("when:date=2006-01-02 15:04")
+
+	assert.Nil(t, err, "ParseRowSortKey should not return a non-nil error")
+	assert.Equal(t, RowSortKey{Column: "when", Comparison: DateComparison, DateLayout: "2006-01-02 15:04"}, key, "The date layout should keep its colons")
+
+	key, err = ParseRowSortKey("Time:Stamp:asc:DATE=15:04")
+
+	assert.Nil(t, err, "ParseRowSortKey should not return a non-nil error")
+	assert.Equal(t, RowSortKey{Column: "Time:Stamp", Comparison: DateComparison, DateLayout: "15:04"}, key)
+}
+
+/* ROW FILTERING */
+func TestConvertRowFilterExpression(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.ExcludedColumns = []string{"Country"}
+	cfg.RowFilterExpression = `(Country == "Chile" or Country = 'Peru') and not Amount < 100 and ` + "`Customer Name`" + ` matches "^[A-M]"`
+
+	expected := `|Customer Name|Amount|
+|:-:|:-:|
+|Ana|$1,200.00|
+|Marco|100|`
+
+	csv := "Customer Name,Country,Amount\nAna,Chile,\"$1,200.00\"\nBruno,Chile,99\nMarco,Peru,100\nNina,Peru,500\nOscar,Chile,n/a\nLuis,Spain,700"
+
+	res, err := Convert(csv, cfg)
+
+	assert.Nil(t, err, "Convert with a row filter expression should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderRowFilterByDateAndFunction(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.RowFilterExpression = `Due >= date("01/03/2023", "02/01/2006") and Event contains "a"`
+	cfg.RowFilter = func(row Row) bool {
+		return row.Value("Event") != "banana"
+	}
+
+	expected := `|Due|Event|
+|:-:|:-:|
+|15/01/2024|apple|
+|01/03/2023|pear|
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Due,Event\n15/01/2024,apple\n28/02/2023,plum\n01/03/2023,pear\n02/03/2023,banana\nsoon,kiwi"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with row filters should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertRowFilterComparingDateLiterals(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.RowFilterExpression = `date("2024-01-01") < date("2024-02-01")`
+
+	res, err := Convert("Name\nJane\nJohn", cfg)
+
+	assert.Nil(t, err, "Convert comparing two date literals should not return a non-nil error")
+	assert.Equal(t, "|Name|\n|:-:|\n|Jane|\n|John|", res, "Every row should be kept when the dates compare true")
+
+	cfg.RowFilterExpression = `date("01/03/2024", "02/01/2006") < date("01/02/2024", "02/01/2006")`
+
+	res, err = Convert("Name\nJane\nJohn", cfg)
+
+	assert.Nil(t, err, "Convert comparing two date literals should not return a non-nil error")
+	assert.Equal(t, "|Name|\n|:-:|", res, "Every row should be dropped when the dates compare false")
+}
+
+func TestConvertRowFilterExpressionErrors(t *testing.T) {
+	cfg := createGenericConfig()
+
+	cfg.RowFilterExpression = `Amount > `
+	_, err := Convert("Name,Amount\nJane,12", cfg)
+	assert.EqualError(t, err, "Configuration error: invalid RowFilterExpression: expected a column or a value at the end of the row filter\n")
+
+	cfg.RowFilterExpression = `Name matches "("`
+	_, err = Convert("Name,Amount\nJane,12", cfg)
+	assert.ErrorContains(t, err, "invalid pattern of matches (position 5)")
+
+	cfg.RowFilterExpression = `Amount contains 12`
+	_, err = Convert("Name,Amount\nJane,12", cfg)
+	assert.ErrorContains(t, err, "contains compares text, not numbers or dates (position 7)")
+
+	cfg.RowFilterExpression = `Price > 10`
+	_, err = Convert("Name,Amount\nJane,12", cfg)
+	assert.EqualError(t, err, "Configuration error: invalid RowFilterExpression: column \"Price\" of the row filter does not exist in the header line\n")
+}
+
+func TestRowLookup(t *testing.T) {
+	row := Row{header: []string{"Name", "Note", "Note"}, record: []string{"Jane", "a", "b"}}
+
+	val, ok := row.Lookup("Note#2")
+	assert.True(t, ok, "The second Note column should be found")
+	assert.Equal(t, "b", val)
+
+	_, ok = row.Lookup("Price")
+	assert.False(t, ok, "Columns missing from the header line should not be found")
+
+	assert.Equal(t, []string{"Jane", "a", "b"}, row.Values())
+}
+
+/* HEADER-LESS CSV */
+func TestConvertHeaderlessNumberedAndSupplied(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.HasHeader = NumberedHeader
+	cfg.HeaderNames = []string{"Time"}
+	cfg.ExcludedColumns = []string{"Column 2"}
+	cfg.SortRows = []RowSortKey{{Column: "Column 3", Comparison: NumericComparison}}
+
+	expected := `|Time|Column 3|
+|:-:|:-:|
+|10:02|7.5|
+|10:00|21.3|`
+
+	res, err := Convert("10:00,temp,21.3\n10:02,temp,7.5", cfg)
+
+	assert.Nil(t, err, "Convert of a header-less CSV should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderHeaderlessLettered(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.HasHeader = LetteredHeader
+	cfg.SortColumns = Descending
+
+	expected := `|  B  |  A  |
+| :-: | :-: |
+|  x  |  1  |
+| yy  |  2  |
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("1,x\n2,yy"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader of a header-less CSV should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertHeaderlessEmptyHeader(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.HasHeader = EmptyHeader
+	cfg.ColumnAlign = map[string]Align{"Column 2": Right}
+
+	expected := `|     |    |
+| :-: | -: |
+|  a  | bb |
+| ccc |  d |`
+
+	res, err := Convert("a,bb\nccc,d", cfg)
+
+	assert.Nil(t, err, "Convert with an empty header should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertHeaderNamesErrors(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.HeaderNames = []string{"Time"}
+
+	_, err := Convert("10:00,21.3", cfg)
+	assert.ErrorContains(t, err, "HeaderNames are only used for a CSV without a header line")
+
+	cfg.HasHeader = NumberedHeader
+	cfg.HeaderNames = []string{"Time", "Value", "Unit"}
+
+	_, err = Convert("10:00,21.3", cfg)
+	assert.ErrorContains(t, err, "3 HeaderNames were given but the CSV has 2 columns")
+}
+
+func TestSpreadsheetColumnName(t *testing.T) {
+	assert.Equal(t, "A", spreadsheetColumnName(0))
+	assert.Equal(t, "Z", spreadsheetColumnName(25))
+	assert.Equal(t, "AA", spreadsheetColumnName(26))
+	assert.Equal(t, "ZZ", spreadsheetColumnName(701))
+	assert.Equal(t, "AAA", spreadsheetColumnName(702))
+}
+
+/* ERRORS */
+func TestParseErrorPosition(t *testing.T) {
+	_, err := Convert("a,b\n1,2\n3,\"4", createGenericConfig())
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "A malformed CSV should return a ParseError")
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Equal(t, 5, parseErr.Column)
+
+	var csvErr *csv.ParseError
+	assert.True(t, errors.As(err, &csvErr), "The ParseError should wrap the error of the CSV reader")
+	assert.ErrorIs(t, err, csv.ErrQuote)
+}
+
+func TestConfigErrorField(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.ColumnOrder = []string{"Email"}
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Name,Phone\nJane,123"), &out, cfg)
+
+	var cfgErr *ConfigError
+	assert.True(t, errors.As(err, &cfgErr), "A column missing from the header line should return a ConfigError")
+	assert.Equal(t, "ColumnOrder", cfgErr.Field)
+
+	cfg = createGenericConfig()
+	cfg.StreamWidthSampleRows = -1
+
+	assert.True(t, errors.As(ValidateConfig(cfg), &cfgErr), "ValidateConfig should return a ConfigError")
+	assert.Equal(t, "StreamWidthSampleRows", cfgErr.Field)
+
+	cfg = createGenericConfig()
+	cfg.ExcludedColumnIndices = []int{2}
+
+	_, err = Convert("Name,Phone\nJane,123", cfg)
+
+	assert.True(t, errors.As(err, &cfgErr), "A column index missing from the header line should return a ConfigError")
+	assert.Equal(t, "ExcludedColumnIndices", cfgErr.Field)
+}
+
+func TestRenderErrorCell(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.NewlinePolicy = NewlineError
+
+	_, err := Convert("Name,Address\nJane,\"1 Main St\nSpringfield\"", cfg)
+
+	var renderErr *RenderError
+	assert.True(t, errors.As(err, &renderErr), "A line break with NewlineError should return a RenderError")
+	assert.Equal(t, RenderError{Row: 1, Column: 2, ColumnName: "Address", Value: "1 Main St\nSpringfield", Err: renderErr.Err}, *renderErr)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	_, err := Parse("", createGenericConfig())
+	assert.ErrorIs(t, err, ErrEmptyInput)
+
+	var out bytes.Buffer
+	err = ConvertReader(strings.NewReader(""), &out, createGenericConfig())
+	assert.ErrorIs(t, err, ErrEmptyInput)
+
+	cfg := createGenericConfig()
+	cfg.ExcludedColumns = []string{"Name"}
+
+	_, err = Parse("Name\nJane", cfg)
+	assert.ErrorIs(t, err, ErrAllColumnsExcluded)
+}
+
+/* LENIENT MODE */
+func TestConvertLenientRepairsAndSkipsRecords(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.Lenient = true
+
+	expected := `|Name|Age|
+|:-:|:-:|
+|Jane|30|
+|John||
+|Mary|41|`
+
+	res, report, err := ConvertWithReport("Name,Age\nJane,30,extra\nJohn\nBad\"Quote,1\nMary,41", cfg)
+
+	assert.Nil(t, err, "ConvertWithReport in lenient mode should not return a non-nil error")
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+
+	lines := make([]int, len(report.Warnings))
+	skipped := make([]bool, len(report.Warnings))
+	for i, warning := range report.Warnings {
+		lines[i] = warning.Line
+		skipped[i] = warning.Skipped
+	}
+
+	assert.Equal(t, []int{2, 3, 4}, lines, "Every malformed record should be reported with its line")
+	assert.Equal(t, []bool{false, false, true}, skipped, "Only the record with a bare quote should be skipped")
+	assert.ErrorIs(t, report.Warnings[2].Err, csv.ErrBareQuote)
+	assert.Equal(t, "record on line 2: wrong number of fields, repaired", report.Warnings[0].String())
+
+	_, err = Convert("Name,Age\nJane,30,extra", createGenericConfig())
+	assert.ErrorIs(t, err, csv.ErrFieldCount, "Malformed records should fail the conversion outside of lenient mode")
+}
+
+func TestConvertReaderLenientMaxWarnings(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Lenient = true
+	cfg.MaxWarnings = 1
+
+	var out bytes.Buffer
+	report, err := ConvertReaderWithReport(strings.NewReader("Name,Age\nJane\nJohn\nMary,41"), &out, cfg)
+
+	assert.ErrorIs(t, err, ErrTooManyWarnings)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "The record exceeding the budget should be reported as a ParseError")
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Len(t, report.Warnings, 2, "The report should be returned along with the error")
+}
+
+func TestConvertReuseRecord(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.CSVReaderConfig.ReuseRecord = true
+
+	expected := `|a|b|
+|:-:|:-:|
+|1|2|
+|3|4|
+|5|6|`
+
+	res, err := Convert("a,b\n1,2\n3,4\n5,6", cfg)
+
+	assert.Nil(t, err, "Convert with ReuseRecord should not return a non-nil error")
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+
+	var out bytes.Buffer
+	err = ConvertReader(strings.NewReader("a,b\n1,2\n3,4\n5,6"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with ReuseRecord should not return a non-nil error")
+	assert.Equal(t, expected+"\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+
+	cfg.Compact = false
+	cfg.HasHeader = NumberedHeader
+
+	res, err = Convert("1,2\n3,4\n5,6", cfg)
+
+	assert.Nil(t, err, "Convert with ReuseRecord should not return a non-nil error")
+
+	out.Reset()
+	err = ConvertReader(strings.NewReader("1,2\n3,4\n5,6"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with ReuseRecord should not return a non-nil error")
+	assert.Equal(t, res+"\n", out.String(), "Convert and ConvertReader should produce the same table")
+	assert.Contains(t, res, "|    1     |    2     |")
+}
+
+/* RAGGED ROWS */
+func TestConvertRaggedRowsPadAndTruncate(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.Compact = true
+	cfg.CSVReaderConfig.FieldsPerRecord = -1
+	cfg.RaggedRows = TruncateRaggedRows
+	cfg.RaggedRowFill = "-"
+
+	expected := `|Name|Age|City|
+|:-:|:-:|:-:|
+|Jane|30|-|
+|John|-|-|
+|Mary|41|Oslo|`
+
+	res, err := Convert("Name,Age,City\nJane,30\nJohn\nMary,41,Oslo,extra,more", cfg)
+
+	assert.Nil(t, err, "Convert with truncated ragged rows should not return a non-nil error")
+
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertReaderRaggedRowsOverflow(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.RaggedRows = OverflowRaggedRows
+	cfg.ColumnAliases = map[string]string{"Overflow": "More"}
+
+	expected := `| Name | Age |    More     |
+| :--: | :-: | :---------: |
+| Jane | 30  |             |
+| John |     |             |
+| Mary | 41  | Oslo; extra |
+`
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Name,Age\nJane,30\nJohn\nMary,41,Oslo,extra"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with an overflow column should not return a non-nil error")
+
+	assert.Equal(t, expected, out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConvertRaggedRowsErrors(t *testing.T) {
+	cfg := createGenericConfig()
+	cfg.CSVReaderConfig.FieldsPerRecord = -1
+
+	_, err := Convert("Name,Age\nJane,30\nJohn", cfg)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "A short record should fail the conversion by default")
+	assert.Equal(t, 3, parseErr.Line)
+	assert.ErrorIs(t, err, csv.ErrFieldCount)
+
+	cfg.RaggedRows = PadRaggedRows
+
+	_, err = Convert("Name,Age\nJane,30\nJohn,31,Oslo", cfg)
+	assert.ErrorContains(t, err, "record on line 3: wrong number of fields", "A long record should fail the conversion when padding")
+}
+
+/* LOGGING */
+func TestConvertLogsToConfigLogger(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := createGenericConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	cfg.VerboseLogging = true
+	cfg.ExcludedColumns = []string{"Age"}
+
+	_, err := Convert("Name,Age\nJane,30\nJohn,25", cfg)
+
+	assert.Nil(t, err, "Convert with a logger should not return a non-nil error")
+	assert.Contains(t, logs.String(), "level=DEBUG msg=\"Parsed CSV\" rows=2")
+	assert.Contains(t, logs.String(), "excluded_columns=[Age]")
+	assert.Contains(t, logs.String(), "column_widths=[4]")
+	assert.False(t, slog.Default().Enabled(context.Background(), slog.LevelDebug), "Verbose logging should not change the level of the default logger")
+}
+
+func TestConvertReaderLogsToConfigLogger(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := createGenericConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	var out bytes.Buffer
+	err := ConvertReader(strings.NewReader("Name,Age\nJane,30"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with a logger should not return a non-nil error")
+	assert.Empty(t, logs.String(), "Debug records should only be logged with VerboseLogging")
+
+	cfg.VerboseLogging = true
+	err = ConvertReader(strings.NewReader("Name,Age\nJane,30"), &out, cfg)
+
+	assert.Nil(t, err, "ConvertReader with a logger should not return a non-nil error")
+	assert.Contains(t, logs.String(), "msg=\"Streaming rows in two passes\"")
+	assert.Contains(t, logs.String(), "msg=\"Converted CSV\" warnings=0")
+}
+
+func TestAllColumnsExcludedWarnsConfigLogger(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := createGenericConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	cfg.ExcludedColumns = []string{"Name"}
+
+	res, err := Convert("Name\nJane", cfg)
+
+	assert.Nil(t, err, "Excluding every column should not return a non-nil error")
+	assert.Equal(t, "", res, STRINGS_SHOULD_BE_THE_SAME)
+	assert.Contains(t, logs.String(), "level=WARN msg=\"All columns were excluded from conversion. Returning an empty string\"")
+}
+
+/* CONVERTER */
+func TestConverterConvert(t *testing.T) {
+	converter, err := NewConverter(WithAlign(Left), WithCaption("People"), WithExcludedColumns("Age"), WithColumnAlias("Name", "Full Name"))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	expected := `<!-- People -->
+| Full Name | City |
+| :-------- | :--- |
+| Jane      | Oslo |
+| John      | Rome |`
+
+	res, err := converter.Convert("Name,Age,City\nJane,30,Oslo\nJohn,25,Rome")
+
+	assert.Nil(t, err, "Converter.Convert should not return a non-nil error")
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+
+	cfg := createGenericConfig()
+	cfg.Align = Left
+	cfg.Caption = "People"
+	cfg.ExcludedColumns = []string{"Age"}
+	cfg.ColumnAliases = map[string]string{"Name": "Full Name"}
+
+	res, err = Convert("Name,Age,City\nJane,30,Oslo\nJohn,25,Rome", cfg)
+
+	assert.Nil(t, err, "Convert should not return a non-nil error")
+	assert.Equal(t, expected, res, "The converter and Convert should produce the same table")
+}
+
+func TestNewConverterInvalidOptions(t *testing.T) {
+	converter, err := NewConverter(WithAlign(Align(7)))
+
+	var cfgErr *ConfigError
+	assert.True(t, errors.As(err, &cfgErr), "NewConverter should return a ConfigError for an invalid option")
+	assert.Equal(t, "Align", cfgErr.Field)
+	assert.Nil(t, converter)
+
+	_, err = NewConverter(WithHeaderNames("Name", "Age"), WithHeader(FirstRecordHeader))
+	assert.ErrorAs(t, err, &cfgErr, "Header names with the first record as header line should be invalid")
+}
+
+func TestConverterConvertRecords(t *testing.T) {
+	converter, err := NewConverter(WithAlign(Left), WithRaggedRows(PadRaggedRows), WithRaggedRowFill("-"))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	records := [][]string{{"Name", "Age"}, {"Jane", "30"}, {"John"}}
+	expected := `| Name | Age |
+| :--- | :-- |
+| Jane | 30  |
+| John | -   |`
+
+	res, err := converter.ConvertRecords(records)
+
+	assert.Nil(t, err, "Converter.ConvertRecords should not return a non-nil error")
+	assert.Equal(t, expected, res, STRINGS_SHOULD_BE_THE_SAME)
+	assert.Equal(t, [][]string{{"Name", "Age"}, {"Jane", "30"}, {"John"}}, records, "The records should not be modified")
+
+	_, err = converter.ConvertRecords(nil)
+	assert.ErrorIs(t, err, ErrEmptyInput)
+
+	converter, _ = NewConverter()
+	_, err = converter.ConvertRecords(records)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "A short record should fail the conversion by default")
+	assert.Equal(t, 3, parseErr.Line, "The line of a record should be its position in the records")
+}
+
+func TestConverterConvertReader(t *testing.T) {
+	converter, err := NewConverter(WithCompact(), WithHeader(LetteredHeader))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	var out bytes.Buffer
+	err = converter.ConvertReader(strings.NewReader("Jane,30\nJohn,25"), &out)
+
+	assert.Nil(t, err, "Converter.ConvertReader should not return a non-nil error")
+	assert.Equal(t, "|A|B|\n|:-:|:-:|\n|Jane|30|\n|John|25|\n", out.String(), STRINGS_SHOULD_BE_THE_SAME)
+}
+
+func TestConverterWithReport(t *testing.T) {
+	converter, err := NewConverter(WithCompact(), WithLenient())
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	res, report, err := converter.ConvertWithReport("Name,Age\nJane\nBad\"Quote,1\nJohn,25")
+
+	assert.Nil(t, err, "Converter.ConvertWithReport in lenient mode should not return a non-nil error")
+	assert.Equal(t, "|Name|Age|\n|:-:|:-:|\n|Jane||\n|John|25|", res, STRINGS_SHOULD_BE_THE_SAME)
+	assert.Len(t, report.Warnings, 2, "Both malformed records should be reported")
+
+	var out bytes.Buffer
+	report, err = converter.ConvertReaderWithReport(strings.NewReader("Name,Age\nJane,30,extra"), &out)
+
+	assert.Nil(t, err, "Converter.ConvertReaderWithReport in lenient mode should not return a non-nil error")
+	assert.Equal(t, []Warning{{Line: 2, Err: report.Warnings[0].Err}}, report.Warnings)
+
+	res, report, err = converter.ConvertRecordsWithReport([][]string{{"Name", "Age"}, {"Jane"}})
+
+	assert.Nil(t, err, "Converter.ConvertRecordsWithReport in lenient mode should not return a non-nil error")
+	assert.Equal(t, "|Name|Age|\n|:-:|:-:|\n|Jane||", res, STRINGS_SHOULD_BE_THE_SAME)
+	assert.Len(t, report.Warnings, 1, "The short record should be reported")
+}
+
+func TestConverterOwnsItsConfig(t *testing.T) {
+	excluded := []string{"Age"}
+	converter, err := NewConverter(WithExcludedColumns(excluded...), WithColumnAlign("Name", Right))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	excluded[0] = "Name"
+	cfg := converter.Config()
+	cfg.ColumnAlign["Name"] = Left
+
+	res, err := converter.Convert("Name,Age\nJane,30")
+
+	assert.Nil(t, err, "Converter.Convert should not return a non-nil error")
+	assert.Equal(t, "| Name |\n| ---: |\n| Jane |", res, "Modifying the options or the config afterwards should not affect the converter")
+}
+
+func TestConverterBindsRowFilterToEveryHeader(t *testing.T) {
+	converter, err := NewConverter(WithCompact(), WithRowFilterExpression(`Name matches "^J"`))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+	assert.NotNil(t, converter.rowFilter, "The expression should be parsed when the converter is created")
+
+	res, err := converter.Convert("Name,Age\nJane,30\nMary,41")
+
+	assert.Nil(t, err, "Converter.Convert should not return a non-nil error")
+	assert.Equal(t, "|Name|Age|\n|:-:|:-:|\n|Jane|30|", res, STRINGS_SHOULD_BE_THE_SAME)
+
+	res, err = converter.ConvertRecords([][]string{{"Age", "Name"}, {"41", "Mary"}, {"25", "John"}})
+
+	assert.Nil(t, err, "Converter.ConvertRecords should not return a non-nil error")
+	assert.Equal(t, "|Age|Name|\n|:-:|:-:|\n|25|John|", res, "The expression should be bound to the header line of every CSV")
+
+	_, err = converter.Convert("Email\njane@example.com")
+
+	var cfgErr *ConfigError
+	assert.True(t, errors.As(err, &cfgErr), "A column of the expression missing from the header line should return a ConfigError")
+	assert.Equal(t, "RowFilterExpression", cfgErr.Field)
+}
+
+func TestConverterConcurrentUse(t *testing.T) {
+	converter, err := NewConverter(WithAutoAlign(), WithSortRows(RowSortKey{Column: "Age", Comparison: NumericComparison}), WithRowFilterExpression("Age > 20"))
+
+	assert.Nil(t, err, "NewConverter with valid options should not return a non-nil error")
+
+	csv := "Name,Age\nJane,30\nJohn,25\nMary,12"
+	expected, err := converter.Convert(csv)
+
+	assert.Nil(t, err, "Converter.Convert should not return a non-nil error")
+
+	var wg sync.WaitGroup
+	results := make([]string, 16)
+
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = converter.Convert(csv)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, res := range results {
+		assert.Equal(t, expected, res, "Concurrent conversions should produce the same table")
+	}
+}
+
 /* HELPER */
 func createGenericConfig() Config {
 	var cfg Config
@@ -0,0 +1,438 @@
+package csv2mdtable
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A table between csv2md marker comments in a Markdown document:
+//
+//	<!-- csv2md src="data/prices.csv" align=left -->
+//	| generated | table |
+//	<!-- /csv2md -->
+type markerBlock struct {
+	// Line number of the opening marker, starting at 1
+	line int
+
+	// Byte offsets of the content between the markers, the marker lines themselves excluded
+	start int
+	end   int
+
+	// Path of the CSV file, as written in the marker
+	src string
+
+	// Config of the block, the base config with the options of the marker applied
+	cfg Config
+
+	// Line ending used by the opening marker line
+	newline string
+}
+
+var (
+	openingMarkerRegex = regexp.MustCompile(`^\s*<!--\s*csv2md(\s.*?)?\s*-->\s*$`)
+	closingMarkerRegex = regexp.MustCompile(`^\s*<!--\s*/csv2md\s*-->\s*$`)
+	markerOptionRegex  = regexp.MustCompile(`^([A-Za-z][\w-]*)(?:=(?:"([^"]*)"|'([^']*)'|(\S+)))?`)
+)
+
+// A table between csv2md marker comments that differs from the table generated from its source
+type StaleTable struct {
+	// Path of the Markdown document, empty if the document was checked in memory
+	Path string
+
+	// Line number of the opening marker, starting at 1
+	Line int
+
+	// Path of the source CSV, as written in the marker
+	Src string
+
+	// Unified diff turning the table in the document into the generated table
+	Diff string
+}
+
+// Regenerate every table between csv2md marker comments of the Markdown document, using Convert.
+// Source paths in the markers are resolved relative to baseDir and must stay inside it, absolute paths,
+// paths starting with ".." and symbolic links leading out of baseDir are rejected. Options in a marker
+// are applied on top of cfg for that block only. Everything outside the markers is left byte-identical.
+func UpdateMarkdown(doc []byte, baseDir string, cfg Config) ([]byte, error) {
+	blocks, err := findMarkerBlocks(doc, cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result bytes.Buffer
+	prevEnd := 0
+
+	for _, block := range blocks {
+		table, err := convertMarkerBlock(block, baseDir)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Write(doc[prevEnd:block.start])
+		result.WriteString(table)
+		prevEnd = block.end
+	}
+
+	result.Write(doc[prevEnd:])
+
+	return result.Bytes(), nil
+}
+
+// Regenerate the tables between csv2md marker comments of the Markdown file at path, see UpdateMarkdown.
+// Source paths are resolved relative to the directory of the file.
+// The file is only written if its content changed, which is reported by the returned boolean.
+func UpdateMarkdownFile(path string, cfg Config) (bool, error) {
+	doc, err := os.ReadFile(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := UpdateMarkdown(doc, filepath.Dir(path), cfg)
+
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if bytes.Equal(doc, updated) {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(path, updated, info.Mode().Perm())
+}
+
+// Regenerate every table between csv2md marker comments of the Markdown document without modifying it,
+// and report the tables that differ from their source. See UpdateMarkdown for how the tables are generated.
+func CheckMarkdown(doc []byte, baseDir string, cfg Config) ([]StaleTable, error) {
+	return checkMarkdown(doc, "", baseDir, cfg)
+}
+
+// Check the marked tables of every Markdown file, see CheckMarkdown.
+// Source paths are resolved relative to the directory of each file.
+func CheckMarkdownFiles(paths []string, cfg Config) ([]StaleTable, error) {
+	var stale []StaleTable
+
+	for _, path := range paths {
+		doc, err := os.ReadFile(path)
+
+		if err != nil {
+			return stale, err
+		}
+
+		fileStale, err := checkMarkdown(doc, path, filepath.Dir(path), cfg)
+
+		if err != nil {
+			return stale, fmt.Errorf("%s: %w", path, err)
+		}
+
+		stale = append(stale, fileStale...)
+	}
+
+	return stale, nil
+}
+
+func checkMarkdown(doc []byte, path string, baseDir string, cfg Config) ([]StaleTable, error) {
+	blocks, err := findMarkerBlocks(doc, cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	name := path
+	if name == "" {
+		name = "document"
+	}
+
+	var stale []StaleTable
+
+	for _, block := range blocks {
+		table, err := convertMarkerBlock(block, baseDir)
+
+		if err != nil {
+			return nil, err
+		}
+
+		current := string(doc[block.start:block.end])
+
+		if current == table {
+			continue
+		}
+
+		stale = append(stale, StaleTable{
+			Path: path,
+			Line: block.line,
+			Src:  block.src,
+			Diff: unifiedDiff(name, name+" (generated from "+block.src+")", current, table, block.line+1),
+		})
+	}
+
+	return stale, nil
+}
+
+// Convert the source CSV of the block into the content to be placed between its markers
+func convertMarkerBlock(block markerBlock, baseDir string) (string, error) {
+	src := filepath.FromSlash(block.src)
+
+	// the markers may come from anyone editing the document, they must not read files outside of baseDir
+	if !filepath.IsLocal(src) {
+		return "", fmt.Errorf("line %d: source %q is not a relative path inside the directory of the document", block.line, block.src)
+	}
+
+	root, err := os.OpenRoot(baseDir)
+
+	if err != nil {
+		return "", fmt.Errorf("line %d: %w", block.line, err)
+	}
+
+	defer root.Close()
+
+	// reading through the root also rejects symbolic links leading out of baseDir
+	csv, err := root.ReadFile(src)
+
+	if err != nil {
+		return "", fmt.Errorf("line %d: %w", block.line, err)
+	}
+
+	table, err := Convert(string(csv), block.cfg)
+
+	if err != nil {
+		return "", fmt.Errorf("line %d: %s: %w", block.line, block.src, err)
+	}
+
+	if table == "" {
+		return "", nil
+	}
+
+	return strings.ReplaceAll(table, "\n", block.newline) + block.newline, nil
+}
+
+// Find every marker block of the document. Markers inside fenced code blocks are ignored.
+func findMarkerBlocks(doc []byte, cfg Config) ([]markerBlock, error) {
+	var blocks []markerBlock
+	var open *markerBlock
+
+	for line := range markdownLines(doc) {
+		if line.inCodeBlock {
+			continue
+		}
+
+		text := strings.TrimRight(line.text, "\r\n")
+
+		if closingMarkerRegex.MatchString(text) {
+			if open == nil {
+				return nil, fmt.Errorf("line %d: closing csv2md marker without an opening marker", line.number)
+			}
+
+			open.end = line.start
+			blocks = append(blocks, *open)
+			open = nil
+			continue
+		}
+
+		match := openingMarkerRegex.FindStringSubmatch(text)
+
+		if match == nil {
+			continue
+		}
+
+		if open != nil {
+			return nil, fmt.Errorf("line %d: csv2md marker opened before the one on line %d was closed", line.number, open.line)
+		}
+
+		block, err := parseOpeningMarker(match[1], cfg)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+
+		block.line = line.number
+		block.start = line.start + len(line.text)
+		block.newline = "\n"
+		if strings.HasSuffix(line.text, "\r\n") {
+			block.newline = "\r\n"
+		}
+
+		open = &block
+	}
+
+	if open != nil {
+		return nil, fmt.Errorf("line %d: csv2md marker is never closed", open.line)
+	}
+
+	return blocks, nil
+}
+
+// Parse the options of an opening marker, e.g. src="data/prices.csv" align=left compact
+func parseOpeningMarker(options string, cfg Config) (markerBlock, error) {
+	block := markerBlock{cfg: cfg}
+	options = strings.TrimSpace(options)
+
+	for options != "" {
+		match := markerOptionRegex.FindStringSubmatch(options)
+
+		if match == nil {
+			return block, fmt.Errorf("malformed csv2md marker option %q", options)
+		}
+
+		key := match[1]
+		val := match[2] + match[3] + match[4]
+
+		// options without a value are switched on, e.g. compact
+		if !strings.Contains(match[0], "=") {
+			val = "true"
+		}
+
+		if key == "src" {
+			block.src = val
+		} else if err := applyMarkerOption(&block.cfg, key, val); err != nil {
+			return block, err
+		}
+
+		options = strings.TrimSpace(options[len(match[0]):])
+	}
+
+	if block.src == "" {
+		return block, fmt.Errorf("csv2md marker is missing the src option")
+	}
+
+	return block, nil
+}
+
+// Apply a single marker option to the config. The options are named after the flags of the csv2md command.
+func applyMarkerOption(cfg *Config, key string, val string) error {
+	var err error
+
+	switch key {
+	case "alias":
+		// clone the map so the base config is not modified
+		cfg.ColumnAliases = maps.Clone(cfg.ColumnAliases)
+		if cfg.ColumnAliases == nil {
+			cfg.ColumnAliases = map[string]string{}
+		}
+
+		for _, pair := range strings.Split(val, ",") {
+			idx := strings.LastIndex(pair, "=")
+
+			if idx < 0 {
+				return fmt.Errorf("alias expects name=alias pairs, got %q", pair)
+			}
+
+			cfg.ColumnAliases[pair[:idx]] = pair[idx+1:]
+		}
+	case "align":
+		cfg.Align, err = ParseAlign(val)
+	case "auto-align":
+		cfg.AutoAlign, err = strconv.ParseBool(val)
+	case "caption":
+		cfg.Caption = val
+	case "column-align":
+		// clone the map so the base config is not modified
+		cfg.ColumnAlign = maps.Clone(cfg.ColumnAlign)
+		if cfg.ColumnAlign == nil {
+			cfg.ColumnAlign = map[string]Align{}
+		}
+
+		for _, pair := range strings.Split(val, ",") {
+			idx := strings.LastIndex(pair, "=")
+
+			if idx < 0 {
+				return fmt.Errorf("column-align expects name=align pairs, got %q", pair)
+			}
+
+			if cfg.ColumnAlign[pair[:idx]], err = ParseAlign(pair[idx+1:]); err != nil {
+				return err
+			}
+		}
+	case "compact":
+		cfg.Compact, err = strconv.ParseBool(val)
+	case "display-width":
+		var displayWidth bool
+		displayWidth, err = strconv.ParseBool(val)
+		cfg.WidthMode = RuneCountWidth
+		if displayWidth {
+			cfg.WidthMode = DisplayWidth
+		}
+	case "duplicate-headers":
+		cfg.DuplicateHeaders, err = ParseDuplicateHeaderPolicy(val)
+	case "delimiter":
+		cfg.CSVReaderConfig.Comma, err = ParseRune(key, val)
+	case "comment":
+		cfg.CSVReaderConfig.Comment, err = ParseRune(key, val)
+	case "exclude":
+		cfg.ExcludedColumns = strings.Split(val, ",")
+	case "exclude-index":
+		cfg.ExcludedColumnIndices = nil
+
+		for _, idx := range strings.Split(val, ",") {
+			colIdx, err := strconv.Atoi(idx)
+			if err != nil {
+				return fmt.Errorf("exclude-index expects comma separated column indices, got %q", idx)
+			}
+
+			cfg.ExcludedColumnIndices = append(cfg.ExcludedColumnIndices, colIdx)
+		}
+	case "header":
+		cfg.HasHeader, err = ParseHeaderPolicy(val)
+	case "header-names":
+		cfg.HeaderNames = strings.Split(val, ",")
+
+		// supplied names imply a CSV without a header line
+		if cfg.HasHeader == FirstRecordHeader {
+			cfg.HasHeader = NumberedHeader
+		}
+	case "include":
+		cfg.IncludedColumns = strings.Split(val, ",")
+	case "order":
+		cfg.ColumnOrder = strings.Split(val, ",")
+	case "fields-per-record":
+		cfg.CSVReaderConfig.FieldsPerRecord, err = strconv.Atoi(val)
+	case "filter":
+		cfg.RowFilterExpression = val
+	case "lazy-quotes":
+		cfg.CSVReaderConfig.LazyQuotes, err = strconv.ParseBool(val)
+	case "newlines":
+		cfg.NewlinePolicy, err = ParseNewlinePolicy(val)
+	case "ragged":
+		cfg.RaggedRows, err = ParseRaggedRowPolicy(val)
+	case "ragged-fill":
+		cfg.RaggedRowFill = val
+	case "sort":
+		cfg.SortColumns, err = ParseColumnSortOption(val)
+	case "sort-rows":
+		cfg.SortRows = nil
+
+		// the keys are separated by semicolons, as date layouts may contain commas, e.g. date=Jan 2, 2006
+		for _, spec := range strings.Split(val, ";") {
+			sortKey, err := ParseRowSortKey(spec)
+			if err != nil {
+				return fmt.Errorf("invalid value for csv2md marker option %q: %w", key, err)
+			}
+
+			cfg.SortRows = append(cfg.SortRows, sortKey)
+		}
+	case "trim-leading-space":
+		cfg.CSVReaderConfig.TrimLeadingSpace, err = strconv.ParseBool(val)
+	default:
+		return fmt.Errorf("unknown csv2md marker option %q", key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value for csv2md marker option %q: %w", key, err)
+	}
+
+	return nil
+}
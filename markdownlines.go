@@ -0,0 +1,77 @@
+package csv2mdtable
+
+import (
+	"bytes"
+	"iter"
+	"strings"
+)
+
+// A line of a Markdown document
+type markdownLine struct {
+	// Line number, starting at 1
+	number int
+
+	// Byte offset of the line in the document
+	start int
+
+	// Content of the line, line ending included
+	text string
+
+	// Is the line part of a fenced code block? The fences themselves are included.
+	inCodeBlock bool
+}
+
+// Iterate over the lines of a Markdown document, keeping track of fenced code blocks
+func markdownLines(doc []byte) iter.Seq[markdownLine] {
+	return func(yield func(markdownLine) bool) {
+		// the fence that opened the current code block, empty outside of code blocks
+		fence := ""
+		offset := 0
+
+		for number := 1; offset < len(doc); number++ {
+			end := bytes.IndexByte(doc[offset:], '\n')
+
+			if end < 0 {
+				end = len(doc)
+			} else {
+				end += offset + 1
+			}
+
+			line := markdownLine{number: number, start: offset, text: string(doc[offset:end])}
+			trimmed := strings.TrimSpace(line.text)
+
+			if fence == "" {
+				if openingFence := codeFence(trimmed); openingFence != "" {
+					fence = openingFence
+					line.inCodeBlock = true
+				}
+			} else {
+				line.inCodeBlock = true
+
+				// the closing fence uses the same character, at least as many times, and nothing else
+				if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+					fence = ""
+				}
+			}
+
+			if !yield(line) {
+				return
+			}
+
+			offset = end
+		}
+	}
+}
+
+// Get the fence (``` or ~~~, possibly longer) starting the line, or an empty string
+func codeFence(trimmedLine string) string {
+	for _, char := range []string{"`", "~"} {
+		fence := trimmedLine[:len(trimmedLine)-len(strings.TrimLeft(trimmedLine, char))]
+
+		if len(fence) >= 3 {
+			return fence
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,229 @@
+package csv2mdtable
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// A pipe table found in a Markdown document
+type markdownTableBlock struct {
+	// Line number of the header line, starting at 1
+	line int
+
+	// Byte offsets of the table in the document, from the header line to the end of the last row.
+	// The caption comment above the header line is not part of it.
+	start int
+	end   int
+
+	// Indentation of the header line
+	indent string
+
+	// Line ending used by the header line
+	newline string
+
+	// Which columns have an explicit alignment in the delimiter row?
+	aligned []bool
+
+	table *Table
+}
+
+var (
+	delimiterCellRegex = regexp.MustCompile(`^:?-+:?$`)
+	captionLineRegex   = regexp.MustCompile(`^[ \t]*<!-- (.+) -->$`)
+)
+
+// Parse the first GFM pipe table of the Markdown text into a Table.
+// Leading and trailing pipes are optional, escaped pipes (\|) are unescaped and a comment right above
+// the header line in the form Convert writes it, "<!-- caption -->", becomes the caption of the table.
+// Columns without an alignment in the delimiter row are aligned left, as GFM renders them.
+func ParseMarkdownTable(md string) (*Table, error) {
+	blocks := findMarkdownTables([]byte(md))
+
+	if len(blocks) == 0 {
+		return nil, errors.New("no Markdown table found")
+	}
+
+	return blocks[0].table, nil
+}
+
+// Convert the first GFM pipe table of the Markdown text back into CSV, see ParseMarkdownTable.
+// The CSV is written with the delimiter set in CSVReaderConfig.Comma, a comma by default.
+func MarkdownToCSV(md string, cfg Config) (string, error) {
+	table, err := ParseMarkdownTable(md)
+
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	csvWriter := csv.NewWriter(&result)
+
+	if cfg.CSVReaderConfig.Comma != 0 {
+		csvWriter.Comma = cfg.CSVReaderConfig.Comma
+	}
+
+	if err := csvWriter.Write(table.Header()); err != nil {
+		return "", fmt.Errorf("Failed to write CSV. Error: %w", err)
+	}
+
+	if err := csvWriter.WriteAll(table.Rows); err != nil {
+		return "", fmt.Errorf("Failed to write CSV. Error: %w", err)
+	}
+
+	return result.String(), nil
+}
+
+// Find every pipe table of the Markdown document. Tables inside fenced code blocks are ignored.
+func findMarkdownTables(doc []byte) []markdownTableBlock {
+	lines := slices.Collect(markdownLines(doc))
+	var blocks []markdownTableBlock
+
+	for idx := 0; idx+1 < len(lines); idx++ {
+		header, delimiter := lines[idx], lines[idx+1]
+
+		if header.inCodeBlock || delimiter.inCodeBlock || !hasUnescapedPipe(header.text) || !hasUnescapedPipe(delimiter.text) {
+			continue
+		}
+
+		headerCells := splitTableRow(header.text)
+		aligns, aligned, ok := parseDelimiterRow(delimiter.text)
+
+		if !ok || len(headerCells) != len(aligns) {
+			continue
+		}
+
+		block := markdownTableBlock{
+			line:    header.number,
+			start:   header.start,
+			indent:  header.text[:len(header.text)-len(strings.TrimLeft(header.text, " \t"))],
+			newline: lineEnding(header.text),
+			aligned: aligned,
+			table:   &Table{},
+		}
+
+		for i, name := range headerCells {
+			block.table.Columns = append(block.table.Columns, Column{Name: name, Align: aligns[i], Width: utf8.RuneCountInString(name)})
+		}
+
+		// a caption comment right above the header line belongs to the table, unless it is a csv2md marker
+		if idx > 0 && !lines[idx-1].inCodeBlock {
+			text := strings.TrimRight(lines[idx-1].text, "\r\n")
+
+			if match := captionLineRegex.FindStringSubmatch(text); match != nil && !openingMarkerRegex.MatchString(text) && !closingMarkerRegex.MatchString(text) {
+				block.table.Caption = match[1]
+			}
+		}
+
+		// the table ends at the first empty line, or line without a pipe
+		idx += 2
+		for ; idx < len(lines) && !lines[idx].inCodeBlock && hasUnescapedPipe(lines[idx].text); idx++ {
+			row := splitTableRow(lines[idx].text)
+
+			// missing cells are empty and excess cells are ignored
+			row = append(row, make([]string, max(len(headerCells)-len(row), 0))...)[:len(headerCells)]
+
+			updateColumnWidths(block.table.Columns, row, RuneCountWidth)
+			block.table.Rows = append(block.table.Rows, row)
+		}
+
+		last := lines[idx-1]
+		block.end = last.start + len(last.text)
+		blocks = append(blocks, block)
+
+		// the loop increments idx, the line ending the table may start the next one
+		idx--
+	}
+
+	return blocks
+}
+
+// Split a row of a pipe table into its unescaped and trimmed cells. \| stands for a pipe and \\ for a backslash,
+// other backslashes are kept as they are.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+
+	var cells []string
+	var cell strings.Builder
+
+	// whether the line ends with a pipe closing the last cell
+	closed := false
+
+	for i := 0; i < len(line); i++ {
+		closed = false
+
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && (line[i+1] == '|' || line[i+1] == '\\'):
+			cell.WriteByte(line[i+1])
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			closed = true
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+
+	if closed {
+		return cells
+	}
+
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// Parse the delimiter row of a pipe table into the alignment of each column.
+// Also reports which columns have an explicit alignment, and false if the line is not a delimiter row.
+func parseDelimiterRow(line string) ([]Align, []bool, bool) {
+	cells := splitTableRow(line)
+	aligns := make([]Align, len(cells))
+	aligned := make([]bool, len(cells))
+
+	for i, cell := range cells {
+		if !delimiterCellRegex.MatchString(cell) {
+			return nil, nil, false
+		}
+
+		starts, ends := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		aligned[i] = starts || ends
+
+		switch {
+		case starts && ends:
+			aligns[i] = Center
+		case ends:
+			aligns[i] = Right
+		default:
+			aligns[i] = Left
+		}
+	}
+
+	return aligns, aligned, true
+}
+
+// Check whether the line contains a pipe that is not escaped
+func hasUnescapedPipe(line string) bool {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			// skip the escaped character
+			i++
+		case '|':
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the line ending of the line, defaulting to a new line character for the last line of a document
+func lineEnding(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
@@ -0,0 +1,259 @@
+package csv2mdtable
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// Option sets an option of the config of a Converter, see NewConverter.
+// Options adding columns or keys add to the ones of earlier options, the other options replace their value.
+type Option func(cfg *Config)
+
+// Start from the given config. Options following it are applied on top.
+func WithConfig(config Config) Option {
+	return func(cfg *Config) {
+		*cfg = cloneConfig(config)
+	}
+}
+
+// Align the content of the table, see Config.Align
+func WithAlign(align Align) Option {
+	return func(cfg *Config) {
+		cfg.Align = align
+	}
+}
+
+// Detect the alignment of each column from its values, see Config.AutoAlign
+func WithAutoAlign() Option {
+	return func(cfg *Config) {
+		cfg.AutoAlign = true
+	}
+}
+
+// Set the caption of the table
+func WithCaption(caption string) Option {
+	return func(cfg *Config) {
+		cfg.Caption = caption
+	}
+}
+
+// Align a specific column, by header name, see Config.ColumnAlign
+func WithColumnAlign(name string, align Align) Option {
+	return func(cfg *Config) {
+		// clone the map so a config handed to WithConfig is not modified
+		cfg.ColumnAlign = maps.Clone(cfg.ColumnAlign)
+		if cfg.ColumnAlign == nil {
+			cfg.ColumnAlign = map[string]Align{}
+		}
+
+		cfg.ColumnAlign[name] = align
+	}
+}
+
+// Display a specific column under another name, see Config.ColumnAliases
+func WithColumnAlias(name string, alias string) Option {
+	return func(cfg *Config) {
+		// clone the map so a config handed to WithConfig is not modified
+		cfg.ColumnAliases = maps.Clone(cfg.ColumnAliases)
+		if cfg.ColumnAliases == nil {
+			cfg.ColumnAliases = map[string]string{}
+		}
+
+		cfg.ColumnAliases[name] = alias
+	}
+}
+
+// Align a specific column, by its index in the CSV (starting at 0), see Config.ColumnIndexAlign
+func WithColumnIndexAlign(idx int, align Align) Option {
+	return func(cfg *Config) {
+		// clone the map so a config handed to WithConfig is not modified
+		cfg.ColumnIndexAlign = maps.Clone(cfg.ColumnIndexAlign)
+		if cfg.ColumnIndexAlign == nil {
+			cfg.ColumnIndexAlign = map[int]Align{}
+		}
+
+		cfg.ColumnIndexAlign[idx] = align
+	}
+}
+
+// Position the columns first, in this order, see Config.ColumnOrder
+func WithColumnOrder(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.ColumnOrder = slices.Concat(cfg.ColumnOrder, names)
+	}
+}
+
+// Render the compact version of the Markdown table
+func WithCompact() Option {
+	return func(cfg *Config) {
+		cfg.Compact = true
+	}
+}
+
+// Set every option of the CSV reader, see Config.CSVReaderConfig
+func WithCSVReaderConfig(readerCfg CSVReaderConfig) Option {
+	return func(cfg *Config) {
+		cfg.CSVReaderConfig = readerCfg
+	}
+}
+
+// Set the field delimiter of the CSV, see CSVReaderConfig.Comma
+func WithDelimiter(delimiter rune) Option {
+	return func(cfg *Config) {
+		cfg.CSVReaderConfig.Comma = delimiter
+	}
+}
+
+// Set what happens to columns sharing a header name, see Config.DuplicateHeaders
+func WithDuplicateHeaders(policy DuplicateHeaderPolicy) Option {
+	return func(cfg *Config) {
+		cfg.DuplicateHeaders = policy
+	}
+}
+
+// Exclude columns by their index in the CSV, starting at 0, see Config.ExcludedColumnIndices
+func WithExcludedColumnIndices(indices ...int) Option {
+	return func(cfg *Config) {
+		cfg.ExcludedColumnIndices = slices.Concat(cfg.ExcludedColumnIndices, indices)
+	}
+}
+
+// Exclude columns by header name, see Config.ExcludedColumns
+func WithExcludedColumns(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.ExcludedColumns = slices.Concat(cfg.ExcludedColumns, names)
+	}
+}
+
+// Set whether the first record of the CSV is the header line, see Config.HasHeader
+func WithHeader(policy HeaderPolicy) Option {
+	return func(cfg *Config) {
+		cfg.HasHeader = policy
+	}
+}
+
+// Name the columns of a CSV without a header line, see Config.HeaderNames.
+// Implies NumberedHeader, unless another header policy is set.
+func WithHeaderNames(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.HeaderNames = slices.Concat(cfg.HeaderNames, names)
+
+		if cfg.HasHeader == FirstRecordHeader {
+			cfg.HasHeader = NumberedHeader
+		}
+	}
+}
+
+// Include only the given columns, see Config.IncludedColumns
+func WithIncludedColumns(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.IncludedColumns = slices.Concat(cfg.IncludedColumns, names)
+	}
+}
+
+// Repair or skip malformed records instead of failing the conversion, see Config.Lenient
+func WithLenient() Option {
+	return func(cfg *Config) {
+		cfg.Lenient = true
+	}
+}
+
+// Write the warnings and diagnostics of the conversions to the logger, see Config.Logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
+
+// Set the number of malformed records tolerated in lenient mode, see Config.MaxWarnings
+func WithMaxWarnings(maxWarnings int) Option {
+	return func(cfg *Config) {
+		cfg.MaxWarnings = maxWarnings
+	}
+}
+
+// Set what happens to line breaks in values, see Config.NewlinePolicy
+func WithNewlinePolicy(policy NewlinePolicy) Option {
+	return func(cfg *Config) {
+		cfg.NewlinePolicy = policy
+	}
+}
+
+// Set what happens to records with another number of fields than the header line, see Config.RaggedRows
+func WithRaggedRows(policy RaggedRowPolicy) Option {
+	return func(cfg *Config) {
+		cfg.RaggedRows = policy
+	}
+}
+
+// Set the value short records are padded with, see Config.RaggedRowFill
+func WithRaggedRowFill(fill string) Option {
+	return func(cfg *Config) {
+		cfg.RaggedRowFill = fill
+	}
+}
+
+// Write the tables with the renderer, see Config.Renderer
+func WithRenderer(renderer Renderer) Option {
+	return func(cfg *Config) {
+		cfg.Renderer = renderer
+	}
+}
+
+// Keep only the data rows the function returns true for, see Config.RowFilter
+func WithRowFilter(filter func(row Row) bool) Option {
+	return func(cfg *Config) {
+		cfg.RowFilter = filter
+	}
+}
+
+// Keep only the data rows matching the expression, see Config.RowFilterExpression
+func WithRowFilterExpression(expression string) Option {
+	return func(cfg *Config) {
+		cfg.RowFilterExpression = expression
+	}
+}
+
+// Sort the columns, see Config.SortColumns
+func WithSortColumns(option ColumnSortOption) Option {
+	return func(cfg *Config) {
+		cfg.SortColumns = option
+	}
+}
+
+// Sort the columns with the function. Sets SortColumns to Custom.
+func WithSortFunction(sortFunction ColumnSortFunction) Option {
+	return func(cfg *Config) {
+		cfg.SortColumns = Custom
+		cfg.SortFunction = sortFunction
+	}
+}
+
+// Sort the data rows by the keys, in order of precedence, see Config.SortRows
+func WithSortRows(keys ...RowSortKey) Option {
+	return func(cfg *Config) {
+		cfg.SortRows = slices.Concat(cfg.SortRows, keys)
+	}
+}
+
+// Set the number of data rows ConvertReader inspects to determine the column widths, see Config.StreamWidthSampleRows
+func WithStreamWidthSampleRows(rows int) Option {
+	return func(cfg *Config) {
+		cfg.StreamWidthSampleRows = rows
+	}
+}
+
+// Log detailed diagnostic messages, see Config.VerboseLogging
+func WithVerboseLogging() Option {
+	return func(cfg *Config) {
+		cfg.VerboseLogging = true
+	}
+}
+
+// Set how the width of the values is measured, see Config.WidthMode
+func WithWidthMode(mode WidthMode) Option {
+	return func(cfg *Config) {
+		cfg.WidthMode = mode
+	}
+}
@@ -0,0 +1,118 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+)
+
+// Renders an Org-mode table, preceded by a row of alignment cookies (<l>, <c> and <r>).
+// The caption of the table becomes a #+CAPTION keyword.
+type OrgTableRenderer struct {
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+func (r OrgTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, getMaxColumnLengths(table, r))
+}
+
+func (r OrgTableRenderer) cellWidth(val string) int {
+	return stringWidth(escapeOrgCell(val), r.WidthMode)
+}
+
+func (r OrgTableRenderer) minColumnWidth(column Column) int {
+	// wide enough for the alignment cookie
+	return 3
+}
+
+func (r OrgTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	if table.Caption != "" {
+		// a line break would end the keyword
+		if _, err := io.WriteString(w, "#+CAPTION: "+strings.Join(strings.Fields(table.Caption), " ")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	cookies := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		cookies[i] = orgAlignCookie(column.Align)
+	}
+
+	cookieLine, err := r.constructDataLine(table, cookies, maxLenOfCol, 0)
+	if err != nil {
+		return err
+	}
+
+	headerLine, err := r.constructDataLine(table, escapeOrgRecord(table.Header()), maxLenOfCol, 0)
+	if err != nil {
+		return err
+	}
+
+	separators := make([]string, len(maxLenOfCol))
+	for i, colLen := range maxLenOfCol {
+		separators[i] = strings.Repeat("-", colLen+2)
+	}
+
+	_, err = io.WriteString(w, cookieLine+headerLine+"|"+strings.Join(separators, "+")+"|\n")
+	return err
+}
+
+func (r OrgTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	line, err := r.constructDataLine(table, escapeOrgRecord(row), maxLenOfCol, rowIdx)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, line)
+	return err
+}
+
+func (r OrgTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+// Construct a line of the table out of escaped values, padded to the width of their column
+func (r OrgTableRenderer) constructDataLine(table *Table, colVals []string, maxLenOfCol []int, currRowIdx int) (string, error) {
+	var line strings.Builder
+	line.WriteString("|")
+
+	for i, val := range colVals {
+		padded, err := padAligned(val, max(maxLenOfCol[i], stringWidth(val, r.WidthMode)), table.Columns[i].Align, r.WidthMode)
+		if err != nil {
+			return "", paddingError(val, table.Columns[i], currRowIdx, i, err)
+		}
+
+		line.WriteString(" " + padded + " |")
+	}
+
+	line.WriteString("\n")
+
+	return line.String(), nil
+}
+
+// Get the alignment cookie of a column
+func orgAlignCookie(align Align) string {
+	switch align {
+	case Left:
+		return "<l>"
+	case Right:
+		return "<r>"
+	}
+
+	return "<c>"
+}
+
+// Escape the values of a record for an Org-mode table
+func escapeOrgRecord(record []string) []string {
+	escaped := make([]string, len(record))
+	for i := range record {
+		escaped[i] = escapeOrgCell(record[i])
+	}
+	return escaped
+}
+
+// Escape the cell separators of a value. Line breaks in the value become spaces, as rows cannot span several lines.
+func escapeOrgCell(val string) string {
+	val = strings.ReplaceAll(val, "|", `\vert{}`)
+	return strings.Join(splitCellLines(val), " ")
+}
@@ -0,0 +1,101 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+)
+
+// Renders a Pandoc grid table. Values spanning several lines are kept on several lines of their cell,
+// so cells can hold block content such as lists or paragraphs. The alignment of every column is marked with colons
+// in the line separating the header from the rows, and the caption of the table is written above it.
+type PandocGridTableRenderer struct {
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+func (r PandocGridTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, getMaxColumnLengths(table, r))
+}
+
+func (r PandocGridTableRenderer) cellWidth(val string) int {
+	width := 0
+	for _, line := range splitCellLines(val) {
+		width = max(width, stringWidth(line, r.WidthMode))
+	}
+	return width
+}
+
+func (r PandocGridTableRenderer) minColumnWidth(column Column) int {
+	return 1
+}
+
+func (r PandocGridTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	var head strings.Builder
+
+	if table.Caption != "" {
+		// the caption is a paragraph, separated from the table by a blank line
+		head.WriteString("Table: " + strings.Join(strings.Fields(table.Caption), " ") + "\n\n")
+	}
+
+	head.WriteString(constructGridBorderLine(maxLenOfCol, '-'))
+
+	lines, err := constructGridRowLines(splitRecordLines(table.Header()), table.Columns, maxLenOfCol, 0, r.WidthMode)
+	if err != nil {
+		return err
+	}
+
+	head.WriteString(lines)
+	head.WriteString(constructPandocHeaderSeparatorLine(table.Columns, maxLenOfCol))
+
+	_, err = io.WriteString(w, head.String())
+	return err
+}
+
+func (r PandocGridTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	lines, err := constructGridRowLines(splitRecordLines(row), table.Columns, maxLenOfCol, rowIdx, r.WidthMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, lines+constructGridBorderLine(maxLenOfCol, '-'))
+	return err
+}
+
+func (r PandocGridTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+// Construct the line separating the header from the rows, e.g. +:=====+=====:+, with the alignment of every column
+// marked the same way as in the separator line of a pipe table
+func constructPandocHeaderSeparatorLine(columns []Column, maxLenOfCol []int) string {
+	var line strings.Builder
+	line.WriteString("+")
+
+	for i, column := range columns {
+		// one space of padding on each side of the values
+		fill := []byte(strings.Repeat("=", maxLenOfCol[i]+2))
+
+		if column.Align == Left || column.Align == Center {
+			fill[0] = ':'
+		}
+
+		if column.Align == Right || column.Align == Center {
+			fill[len(fill)-1] = ':'
+		}
+
+		line.WriteString(string(fill) + "+")
+	}
+
+	line.WriteString("\n")
+
+	return line.String()
+}
+
+// Split every value of a record into its lines
+func splitRecordLines(record []string) [][]string {
+	cells := make([][]string, len(record))
+	for i, val := range record {
+		cells[i] = splitCellLines(val)
+	}
+	return cells
+}
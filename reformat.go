@@ -0,0 +1,147 @@
+package csv2mdtable
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Re-render every pipe table of the Markdown document the way Convert formats its output.
+// Tables inside fenced code blocks and between csv2md marker comments (which belong to UpdateMarkdown) are left alone.
+// The alignment of the columns comes from Align, ColumnAlign, ColumnIndexAlign and AutoAlign, unless
+// PreserveAlignment is set, which keeps the explicit alignments of the delimiter rows. Compact selects the compact
+// syntax. The other options of the config do not apply to existing tables.
+// Everything outside the tables is left byte-identical.
+func FormatMarkdown(doc []byte, cfg Config) ([]byte, error) {
+	cfgErr := ValidateConfig(cfg)
+
+	if cfgErr != nil {
+		return nil, fmt.Errorf("Configuration error: %w\n", cfgErr)
+	}
+
+	markerBlocks, err := findMarkerBlocks(doc, cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result bytes.Buffer
+	prevEnd := 0
+
+	for _, block := range findMarkdownTables(doc) {
+		if insideMarkerBlock(block, markerBlocks) {
+			continue
+		}
+
+		formatted, err := formatMarkdownTable(block, cfg)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", block.line, err)
+		}
+
+		// the last line of the document may not end with a new line
+		if !bytes.HasSuffix(doc[:block.end], []byte("\n")) {
+			formatted = strings.TrimSuffix(formatted, block.newline)
+		}
+
+		result.Write(doc[prevEnd:block.start])
+		result.WriteString(formatted)
+		prevEnd = block.end
+	}
+
+	result.Write(doc[prevEnd:])
+
+	return result.Bytes(), nil
+}
+
+// Re-render the pipe tables of the Markdown file at path, see FormatMarkdown.
+// The file is only written if its content changed, which is reported by the returned boolean.
+func FormatMarkdownFile(path string, cfg Config) (bool, error) {
+	doc, err := os.ReadFile(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := FormatMarkdown(doc, cfg)
+
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if bytes.Equal(doc, formatted) {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(path, formatted, info.Mode().Perm())
+}
+
+// Render a table found in a document, with the indentation and line endings of the original
+func formatMarkdownTable(block markdownTableBlock, cfg Config) (string, error) {
+	table := block.table
+
+	// the columns of a parsed Markdown table are in the order of the document
+	columnIndices := make([]int, len(table.Columns))
+	parsedAligns := make([]Align, len(table.Columns))
+	for i := range columnIndices {
+		columnIndices[i] = i
+		parsedAligns[i] = table.Columns[i].Align
+	}
+
+	if cfg.AutoAlign {
+		detectColumnAlignments(table, columnIndices, cfg)
+	} else {
+		alignColumns(table.Columns, columnIndices, cfg, nil)
+	}
+
+	if cfg.PreserveAlignment {
+		for i := range table.Columns {
+			if block.aligned[i] {
+				table.Columns[i].Align = parsedAligns[i]
+			}
+		}
+	}
+
+	// the comment above the table stays as it is in the document, whether it is a caption or not
+	table.Caption = ""
+
+	var rendered strings.Builder
+
+	var renderer Renderer = PipeTableRenderer{WidthMode: cfg.WidthMode}
+	if cfg.Compact {
+		renderer = CompactPipeTableRenderer{}
+	}
+
+	if err := renderer.Render(&rendered, table); err != nil {
+		return "", err
+	}
+
+	lines := strings.SplitAfter(rendered.String(), "\n")
+
+	var formatted strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		formatted.WriteString(block.indent + strings.TrimSuffix(line, "\n") + block.newline)
+	}
+
+	return formatted.String(), nil
+}
+
+// Check whether the table is located between the markers of a csv2md block
+func insideMarkerBlock(table markdownTableBlock, markerBlocks []markerBlock) bool {
+	for _, marker := range markerBlocks {
+		if table.start >= marker.start && table.end <= marker.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package csv2mdtable
+
+import (
+	"fmt"
+	"io"
+)
+
+// Renderer writes a parsed table in a specific output syntax.
+// Implement it to add new output dialects and set it as Config.Renderer to use it with Convert.
+type Renderer interface {
+	Render(w io.Writer, table *Table) error
+}
+
+// rowRenderer is implemented by renderers that can write a table one row at a time.
+// ConvertReader streams the rows through it instead of collecting the whole table in memory.
+type rowRenderer interface {
+	Renderer
+
+	// Write everything that comes before the first data row. maxLenOfCol holds the width of each column.
+	writeHead(w io.Writer, table *Table, maxLenOfCol []int) error
+
+	// Write a single data row. rowIdx starts at 1, 0 being the header line.
+	writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error
+
+	// Write everything that comes after the last data row. maxLenOfCol holds the width of each column.
+	writeTail(w io.Writer, table *Table, maxLenOfCol []int) error
+}
+
+// columnMeasurer is implemented by renderers that pad their cells, which requires the width of every column
+// to be known before the head of the table is written
+type columnMeasurer interface {
+	// Get the width the value takes up once rendered
+	cellWidth(val string) int
+
+	// Get the smallest width the column can be rendered with
+	minColumnWidth(column Column) int
+}
+
+// Renders a beautified Markdown pipe table, with the cells of every column padded to the same width
+type PipeTableRenderer struct {
+	// What happens to line breaks in values
+	NewlinePolicy NewlinePolicy
+
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+// Renders a compact Markdown pipe table, without any padding
+type CompactPipeTableRenderer struct {
+	// What happens to line breaks in values
+	NewlinePolicy NewlinePolicy
+}
+
+// Get the renderer to be used for the config
+func rendererFor(cfg Config) Renderer {
+	if cfg.Renderer != nil {
+		return cfg.Renderer
+	}
+
+	if cfg.Compact {
+		return CompactPipeTableRenderer{NewlinePolicy: cfg.NewlinePolicy}
+	}
+
+	return PipeTableRenderer{NewlinePolicy: cfg.NewlinePolicy, WidthMode: cfg.WidthMode}
+}
+
+func (r PipeTableRenderer) Render(w io.Writer, table *Table) error {
+	// max length of each column so we can beautify the table
+	maxLenOfCol := getMaxColumnLengths(table, r)
+
+	return renderRows(r, w, table, maxLenOfCol)
+}
+
+func (r PipeTableRenderer) cellWidth(val string) int {
+	return stringWidth(escapeCell(val, r.NewlinePolicy), r.WidthMode)
+}
+
+func (r PipeTableRenderer) minColumnWidth(column Column) int {
+	if column.Align == Center {
+		// if align is center, we need at least 3 characters (:-:)
+		return 3
+	}
+
+	// left or right alignment needs at least 2 characters (:- or -:)
+	return 2
+}
+
+func (r PipeTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	if err := writeCaptionComment(w, table.Caption); err != nil {
+		return err
+	}
+
+	if err := r.writeRow(w, table, table.Header(), maxLenOfCol, 0); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, constructBeautifulSeparatorLine(table.Columns, maxLenOfCol))
+	return err
+}
+
+func (r PipeTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	escaped, err := escapeRecord(row, table.Columns, r.NewlinePolicy, rowIdx)
+	if err != nil {
+		return err
+	}
+
+	convertedLine, err := constructBeautifulDataLine(escaped, table.Columns, maxLenOfCol, rowIdx, r.WidthMode)
+
+	if err != nil {
+		return err
+	}
+
+	return writeLine(w, convertedLine)
+}
+
+func (r PipeTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+func (r CompactPipeTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, nil)
+}
+
+func (r CompactPipeTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	if err := writeCaptionComment(w, table.Caption); err != nil {
+		return err
+	}
+
+	if err := r.writeRow(w, table, table.Header(), maxLenOfCol, 0); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, constructCompactSeparatorLine(table.Columns))
+	return err
+}
+
+func (r CompactPipeTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	escaped, err := escapeRecord(row, table.Columns, r.NewlinePolicy, rowIdx)
+	if err != nil {
+		return err
+	}
+
+	return writeLine(w, constructCompactDataLine(escaped))
+}
+
+func (r CompactPipeTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+// Write the head, every row and the tail of the table through a row renderer
+func renderRows(r rowRenderer, w io.Writer, table *Table, maxLenOfCol []int) error {
+	if err := r.writeHead(w, table, maxLenOfCol); err != nil {
+		return err
+	}
+
+	for idx, row := range table.Rows {
+		if err := r.writeRow(w, table, row, maxLenOfCol, idx+1); err != nil {
+			return err
+		}
+	}
+
+	return r.writeTail(w, table, maxLenOfCol)
+}
+
+// Write the caption of the table as an HTML comment, if there is one
+func writeCaptionComment(w io.Writer, caption string) error {
+	if caption == "" {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "<!-- %s -->\n", caption)
+	return err
+}
@@ -0,0 +1,37 @@
+package csv2mdtable
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned in lenient mode when the input holds more malformed records than Config.MaxWarnings
+var ErrTooManyWarnings = errors.New("too many malformed records")
+
+// What happened to the malformed records of a conversion in lenient mode, see Config.Lenient
+type Report struct {
+	// Every malformed record that was repaired or skipped, in the order of the input
+	Warnings []Warning
+}
+
+// A malformed record that was repaired or skipped in lenient mode
+type Warning struct {
+	// Line of the input the record starts at, starting at 1
+	Line int
+
+	// Whether the record was left out of the table. Records with the wrong number of fields are
+	// repaired instead, by padding them with empty values or dropping their extra values.
+	Skipped bool
+
+	// What is wrong with the record, a *csv.ParseError
+	Err error
+}
+
+func (w Warning) String() string {
+	action := "repaired"
+	if w.Skipped {
+		action = "skipped"
+	}
+
+	return fmt.Sprintf("%s, %s", w.Err, action)
+}
@@ -0,0 +1,609 @@
+package csv2mdtable
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A data row of the CSV, as passed to Config.RowFilter
+type Row struct {
+	header []string
+	record []string
+}
+
+// Get the value of a column, by header name as found in the CSV (name#2 for the second column with the name).
+// Excluded columns can be looked up as well. Returns false if there is no such column.
+func (r Row) Lookup(column string) (string, bool) {
+	indices := findColumnIndices(column, r.header)
+
+	if len(indices) == 0 || indices[0] >= len(r.record) {
+		return "", false
+	}
+
+	return r.record[indices[0]], true
+}
+
+// Get the value of a column, or an empty string if there is no such column. See Lookup.
+func (r Row) Value(column string) string {
+	val, _ := r.Lookup(column)
+	return val
+}
+
+// Get every value of the row, in the order of the CSV header line
+func (r Row) Values() []string {
+	values := make([]string, len(r.record))
+	copy(values, r.record)
+	return values
+}
+
+// Create the function deciding which records of the CSV are kept, combining RowFilter and RowFilterExpression,
+// parsed as rowFilter. Returns nil if neither is set.
+func newRecordFilter(cfg Config, rowFilter filterNode, headerLine []string) (func(record []string) bool, error) {
+	var matchesExpression func(record []string) bool
+
+	if rowFilter != nil {
+		var err error
+		if matchesExpression, err = rowFilter.bind(headerLine); err != nil {
+			return nil, configErrorf("RowFilterExpression", "invalid RowFilterExpression: %w", err)
+		}
+	}
+
+	switch {
+	case cfg.RowFilter == nil:
+		return matchesExpression, nil
+	case matchesExpression == nil:
+		return func(record []string) bool { return cfg.RowFilter(Row{header: headerLine, record: record}) }, nil
+	}
+
+	return func(record []string) bool {
+		return matchesExpression(record) && cfg.RowFilter(Row{header: headerLine, record: record})
+	}, nil
+}
+
+// A node of a parsed row filter expression
+type filterNode interface {
+	// Resolve the columns of the node in the header line, returning the predicate evaluating the node on a record
+	bind(header []string) (func(record []string) bool, error)
+}
+
+type andNode struct {
+	left, right filterNode
+}
+
+type orNode struct {
+	left, right filterNode
+}
+
+type notNode struct {
+	operand filterNode
+}
+
+// A comparison of two operands, e.g. Amount > 1000
+type comparisonNode struct {
+	left, right filterOperand
+	operator    string
+
+	// compiled regular expression of the matches operator
+	pattern *regexp.Regexp
+}
+
+type operandKind int
+
+const (
+	columnOperand operandKind = iota
+	stringOperand
+	numberOperand
+	dateOperand
+)
+
+// An operand of a comparison: a column, or a string, number or date literal
+type filterOperand struct {
+	kind operandKind
+
+	// name of the column, or text of the literal
+	text string
+
+	number float64
+	date   time.Time
+
+	// layout of the date literal, which the values of the compared column are parsed with
+	layout string
+}
+
+func (n andNode) bind(header []string) (func(record []string) bool, error) {
+	left, right, err := bindBoth(n.left, n.right, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string) bool { return left(record) && right(record) }, nil
+}
+
+func (n orNode) bind(header []string) (func(record []string) bool, error) {
+	left, right, err := bindBoth(n.left, n.right, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string) bool { return left(record) || right(record) }, nil
+}
+
+func (n notNode) bind(header []string) (func(record []string) bool, error) {
+	operand, err := n.operand.bind(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string) bool { return !operand(record) }, nil
+}
+
+func (n comparisonNode) bind(header []string) (func(record []string) bool, error) {
+	left, err := n.left.bind(header)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.bind(header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n.operator == "contains":
+		return func(record []string) bool { return strings.Contains(left(record), right(record)) }, nil
+	case n.operator == "matches":
+		return func(record []string) bool { return n.pattern.MatchString(left(record)) }, nil
+	case n.left.kind == numberOperand || n.right.kind == numberOperand:
+		return func(record []string) bool {
+			a, okA := parseNumericValue(strings.TrimSpace(left(record)))
+			b, okB := parseNumericValue(strings.TrimSpace(right(record)))
+
+			// values that are not numbers never match
+			return okA && okB && compareResultMatches(n.operator, compareFloats(a, b))
+		}, nil
+	case n.left.kind == dateOperand || n.right.kind == dateOperand:
+		// both layouts are the same if both operands are dates, see parseComparison
+		layout := n.left.layout
+		if layout == "" {
+			layout = n.right.layout
+		}
+
+		return func(record []string) bool {
+			a, errA := time.Parse(layout, strings.TrimSpace(left(record)))
+			b, errB := time.Parse(layout, strings.TrimSpace(right(record)))
+
+			// values that are not dates never match
+			return errA == nil && errB == nil && compareResultMatches(n.operator, a.Compare(b))
+		}, nil
+	}
+
+	return func(record []string) bool {
+		return compareResultMatches(n.operator, strings.Compare(left(record), right(record)))
+	}, nil
+}
+
+// Get the function returning the value of the operand for a record
+func (o filterOperand) bind(header []string) (func(record []string) string, error) {
+	if o.kind != columnOperand {
+		return func(record []string) string { return o.text }, nil
+	}
+
+	indices := findColumnIndices(o.text, header)
+
+	switch {
+	case len(indices) == 0:
+		return nil, fmt.Errorf("column %q of the row filter does not exist in the header line", o.text)
+	case len(indices) > 1:
+		return nil, fmt.Errorf("column %q of the row filter is ambiguous, refer to a single column with %s#n", o.text, o.text)
+	}
+
+	return func(record []string) string { return record[indices[0]] }, nil
+}
+
+// Bind the two operands of a binary node
+func bindBoth(left filterNode, right filterNode, header []string) (func(record []string) bool, func(record []string) bool, error) {
+	leftPredicate, err := left.bind(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rightPredicate, err := right.bind(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return leftPredicate, rightPredicate, nil
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// Check whether the result of a comparison (-1, 0 or 1) satisfies the operator
+func compareResultMatches(operator string, res int) bool {
+	switch operator {
+	case "==":
+		return res == 0
+	case "!=":
+		return res != 0
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	}
+
+	return false
+}
+
+// A token of a row filter expression
+type filterToken struct {
+	// one of: word, string, number, column (quoted with backticks), operator, ( ) and ,
+	kind string
+	text string
+
+	// position of the token in the expression, for error messages
+	pos int
+}
+
+// Parses row filter expressions such as Country == "Chile" and (Amount > 1000 or not Paid == "yes")
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// Parse a row filter expression. The grammar, from lowest to highest precedence:
+//
+//	expression := and { "or" and }
+//	and        := not { "and" not }
+//	not        := "not" not | "(" expression ")" | comparison
+//	comparison := operand ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "contains" | "matches" ) operand
+//	operand    := column | `quoted column` | "string" | 'string' | number | date("2024-01-31" [, "layout"])
+func parseRowFilterExpression(expr string) (filterNode, error) {
+	tokens, err := tokenizeRowFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &filterParser{tokens: tokens}
+
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, parser.errorf("unexpected %q", parser.tokens[parser.pos].text)
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.acceptKeyword("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand: operand}, nil
+	}
+
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(")") {
+			return nil, p.errorf("expected )")
+		}
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("expected a comparison operator")
+	}
+
+	operator := p.tokens[p.pos]
+	node := comparisonNode{left: left, operator: operator.text}
+
+	switch {
+	case operator.kind == "operator":
+	case operator.kind == "word" && (strings.EqualFold(operator.text, "contains") || strings.EqualFold(operator.text, "matches")):
+		node.operator = strings.ToLower(operator.text)
+	default:
+		return nil, p.errorf("expected a comparison operator, got %q", operator.text)
+	}
+
+	p.pos++
+
+	if node.right, err = p.parseOperand(); err != nil {
+		return nil, err
+	}
+
+	if node.operator == "contains" || node.operator == "matches" {
+		if node.left.kind == numberOperand || node.left.kind == dateOperand || node.right.kind == numberOperand || node.right.kind == dateOperand {
+			return nil, fmt.Errorf("%s compares text, not numbers or dates (position %d)", node.operator, operator.pos)
+		}
+	}
+
+	if node.operator == "matches" {
+		if node.right.kind != stringOperand {
+			return nil, fmt.Errorf("matches expects a string pattern (position %d)", operator.pos)
+		}
+
+		if node.pattern, err = regexp.Compile(node.right.text); err != nil {
+			return nil, fmt.Errorf("invalid pattern of matches (position %d): %w", operator.pos, err)
+		}
+	}
+
+	if (node.left.kind == numberOperand && node.right.kind == dateOperand) || (node.left.kind == dateOperand && node.right.kind == numberOperand) {
+		return nil, fmt.Errorf("cannot compare a number with a date (position %d)", operator.pos)
+	}
+
+	if node.left.kind == dateOperand && node.right.kind == dateOperand && node.left.layout != node.right.layout {
+		return nil, fmt.Errorf("cannot compare dates of different layouts (position %d)", operator.pos)
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) parseOperand() (filterOperand, error) {
+	if p.pos >= len(p.tokens) {
+		return filterOperand{}, p.errorf("expected a column or a value")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case "string":
+		return filterOperand{kind: stringOperand, text: token.text}, nil
+	case "number":
+		number, _ := parseNumericValue(token.text)
+		return filterOperand{kind: numberOperand, text: token.text, number: number}, nil
+	case "column":
+		return filterOperand{kind: columnOperand, text: token.text}, nil
+	case "word":
+		if strings.EqualFold(token.text, "date") && p.accept("(") {
+			return p.parseDate()
+		}
+
+		if isFilterKeyword(token.text) {
+			return filterOperand{}, fmt.Errorf("expected a column or a value, got %q (position %d)", token.text, token.pos)
+		}
+
+		return filterOperand{kind: columnOperand, text: token.text}, nil
+	}
+
+	return filterOperand{}, fmt.Errorf("expected a column or a value, got %q (position %d)", token.text, token.pos)
+}
+
+// Parse the arguments of a date literal, after its opening parenthesis
+func (p *filterParser) parseDate() (filterOperand, error) {
+	operand := filterOperand{kind: dateOperand, layout: time.DateOnly}
+
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "string" {
+		return operand, p.errorf("date expects a string")
+	}
+
+	operand.text = p.tokens[p.pos].text
+	p.pos++
+
+	if p.accept(",") {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "string" {
+			return operand, p.errorf("date expects a string layout")
+		}
+
+		operand.layout = p.tokens[p.pos].text
+		p.pos++
+	}
+
+	if !p.accept(")") {
+		return operand, p.errorf("expected )")
+	}
+
+	date, err := time.Parse(operand.layout, operand.text)
+	if err != nil {
+		return operand, fmt.Errorf("%q is not a date of layout %q", operand.text, operand.layout)
+	}
+
+	operand.date = date
+
+	return operand, nil
+}
+
+// Consume the next token if it is the given punctuation
+func (p *filterParser) accept(text string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// Consume the next token if it is the given keyword, case-insensitive
+func (p *filterParser) acceptKeyword(keyword string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "word" && strings.EqualFold(p.tokens[p.pos].text, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// Create an error located at the current token
+func (p *filterParser) errorf(format string, args ...any) error {
+	pos := -1
+	if p.pos < len(p.tokens) {
+		pos = p.tokens[p.pos].pos
+	}
+
+	if pos < 0 {
+		return fmt.Errorf(format+" at the end of the row filter", args...)
+	}
+
+	return fmt.Errorf(format+" (position %d)", append(args, pos)...)
+}
+
+func isFilterKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "contains", "matches":
+		return true
+	}
+
+	return false
+}
+
+// Split a row filter expression into tokens
+func tokenizeRowFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+
+	for pos := 0; pos < len(expr); {
+		c := expr[pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, filterToken{kind: string(c), text: string(c), pos: pos})
+			pos++
+		case strings.ContainsRune("=!<>", rune(c)):
+			operator := string(c)
+			if pos+1 < len(expr) && expr[pos+1] == '=' {
+				operator += "="
+			}
+
+			start := pos
+			pos += len(operator)
+
+			switch operator {
+			case "=":
+				// a single equal sign is accepted as well
+				operator = "=="
+			case "!":
+				return nil, fmt.Errorf("unexpected ! (position %d), use not or !=", start)
+			}
+
+			tokens = append(tokens, filterToken{kind: "operator", text: operator, pos: start})
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(expr[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string (position %d)", pos)
+			}
+
+			text, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, filterToken{kind: "string", text: text, pos: pos})
+			pos += len(quoted)
+		case c == '\'':
+			// single quoted strings have no escape sequences, which suits marker options quoted with double quotes
+			end := strings.IndexByte(expr[pos+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string (position %d)", pos)
+			}
+
+			tokens = append(tokens, filterToken{kind: "string", text: expr[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+		case c == '`':
+			end := strings.IndexByte(expr[pos+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated column name (position %d)", pos)
+			}
+
+			tokens = append(tokens, filterToken{kind: "column", text: expr[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+		default:
+			end := pos + strings.IndexFunc(expr[pos:], isFilterDelimiter)
+			if end < pos {
+				end = len(expr)
+			}
+
+			if end == pos {
+				return nil, fmt.Errorf("unexpected %q (position %d)", expr[pos:pos+1], pos)
+			}
+
+			word := expr[pos:end]
+			kind := "word"
+			if _, ok := parseNumericValue(word); ok && (unicode.IsDigit(rune(word[0])) || word[0] == '-' || word[0] == '.') {
+				kind = "number"
+			}
+
+			tokens = append(tokens, filterToken{kind: kind, text: word, pos: pos})
+			pos = end
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("row filter is empty")
+	}
+
+	return tokens, nil
+}
+
+// Check whether the rune ends a word or a number of a row filter expression
+func isFilterDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("()=!<>\"'`,", r)
+}
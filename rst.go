@@ -0,0 +1,191 @@
+package csv2mdtable
+
+import (
+	"io"
+	"strings"
+)
+
+// Renders a reStructuredText grid table. Values spanning several lines are kept on several lines of their cell.
+// The caption of the table becomes the title of a table directive.
+type RSTGridTableRenderer struct {
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+// Renders a reStructuredText simple table. Line breaks in the values become spaces, as simple tables
+// cannot hold values spanning several lines. The caption of the table becomes the title of a table directive.
+type RSTSimpleTableRenderer struct {
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+// Indentation of a table nested in a table directive
+const rstDirectiveIndent = "   "
+
+func (r RSTGridTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, getMaxColumnLengths(table, r))
+}
+
+func (r RSTGridTableRenderer) cellWidth(val string) int {
+	width := 0
+	for _, line := range splitCellLines(escapeRSTCell(val)) {
+		width = max(width, stringWidth(line, r.WidthMode))
+	}
+	return width
+}
+
+func (r RSTGridTableRenderer) minColumnWidth(column Column) int {
+	return 1
+}
+
+func (r RSTGridTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	if err := writeRSTTableDirective(w, table.Caption); err != nil {
+		return err
+	}
+
+	indent := rstIndent(table)
+
+	if err := writeIndentedLines(w, indent, constructGridBorderLine(maxLenOfCol, '-')); err != nil {
+		return err
+	}
+
+	lines, err := r.constructRowLines(table, table.Header(), maxLenOfCol, 0)
+	if err != nil {
+		return err
+	}
+
+	return writeIndentedLines(w, indent, lines+constructGridBorderLine(maxLenOfCol, '='))
+}
+
+func (r RSTGridTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	lines, err := r.constructRowLines(table, row, maxLenOfCol, rowIdx)
+	if err != nil {
+		return err
+	}
+
+	return writeIndentedLines(w, rstIndent(table), lines+constructGridBorderLine(maxLenOfCol, '-'))
+}
+
+func (r RSTGridTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return nil
+}
+
+func (r RSTGridTableRenderer) constructRowLines(table *Table, row []string, maxLenOfCol []int, rowIdx int) (string, error) {
+	cells := make([][]string, len(row))
+	for i, val := range row {
+		cells[i] = splitCellLines(escapeRSTCell(val))
+	}
+
+	return constructGridRowLines(cells, table.Columns, maxLenOfCol, rowIdx, r.WidthMode)
+}
+
+func (r RSTSimpleTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, getMaxColumnLengths(table, r))
+}
+
+func (r RSTSimpleTableRenderer) cellWidth(val string) int {
+	return stringWidth(escapeRSTSimpleCell(val), r.WidthMode)
+}
+
+func (r RSTSimpleTableRenderer) minColumnWidth(column Column) int {
+	return 1
+}
+
+func (r RSTSimpleTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	if err := writeRSTTableDirective(w, table.Caption); err != nil {
+		return err
+	}
+
+	border := r.constructBorderLine(maxLenOfCol)
+
+	line, err := r.constructDataLine(table, table.Header(), maxLenOfCol, 0)
+	if err != nil {
+		return err
+	}
+
+	return writeIndentedLines(w, rstIndent(table), border+line+border)
+}
+
+func (r RSTSimpleTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	line, err := r.constructDataLine(table, row, maxLenOfCol, rowIdx)
+	if err != nil {
+		return err
+	}
+
+	return writeIndentedLines(w, rstIndent(table), line)
+}
+
+func (r RSTSimpleTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	return writeIndentedLines(w, rstIndent(table), r.constructBorderLine(maxLenOfCol))
+}
+
+// Construct a border line of the simple table, made of = characters under every column
+func (r RSTSimpleTableRenderer) constructBorderLine(maxLenOfCol []int) string {
+	borders := make([]string, len(maxLenOfCol))
+	for i, colLen := range maxLenOfCol {
+		borders[i] = strings.Repeat("=", colLen)
+	}
+
+	return strings.Join(borders, "  ") + "\n"
+}
+
+// Construct a line of the simple table, with the values padded to the width of their column
+func (r RSTSimpleTableRenderer) constructDataLine(table *Table, row []string, maxLenOfCol []int, rowIdx int) (string, error) {
+	cells := make([]string, len(row))
+
+	for i, val := range row {
+		val = escapeRSTSimpleCell(val)
+
+		// a line starting with a blank is read as the continuation of the previous row
+		if i == 0 && val == "" {
+			val = `\`
+		}
+
+		padded, err := padAligned(val, max(maxLenOfCol[i], stringWidth(val, r.WidthMode)), table.Columns[i].Align, r.WidthMode)
+		if err != nil {
+			return "", paddingError(val, table.Columns[i], rowIdx, i, err)
+		}
+
+		cells[i] = padded
+	}
+
+	return strings.Join(cells, "  ") + "\n", nil
+}
+
+// Write the table directive holding the caption of the table, if there is one
+func writeRSTTableDirective(w io.Writer, caption string) error {
+	if caption == "" {
+		return nil
+	}
+
+	// the title of the directive must fit on a single line
+	_, err := io.WriteString(w, ".. table:: "+strings.Join(strings.Fields(caption), " ")+"\n\n")
+	return err
+}
+
+// Get the indentation of the lines of the table, which are nested in a table directive if the table has a caption
+func rstIndent(table *Table) string {
+	if table.Caption == "" {
+		return ""
+	}
+
+	return rstDirectiveIndent
+}
+
+// Escape the characters starting inline markup in reStructuredText
+func escapeRSTCell(val string) string {
+	return rstInlineMarkupReplacer.Replace(val)
+}
+
+// Escape a value of a simple table, which must fit on a single line
+func escapeRSTSimpleCell(val string) string {
+	return strings.Join(splitCellLines(escapeRSTCell(val)), " ")
+}
+
+var rstInlineMarkupReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"`", "\\`",
+	"|", `\|`,
+	"_", `\_`,
+)
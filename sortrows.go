@@ -0,0 +1,335 @@
+package csv2mdtable
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type RowComparison int
+
+const (
+	// Compare the values byte by byte
+	StringComparison RowComparison = 0
+
+	// Compare the values ignoring case
+	CaseInsensitiveComparison RowComparison = 1
+
+	// Compare the runs of digits of the values by their numeric value, so that file2 sorts before file10
+	NaturalComparison RowComparison = 2
+
+	// Compare the values as numbers, amounts of money or percentages, e.g. 1,234.50, $12 or 15%
+	NumericComparison RowComparison = 3
+
+	// Compare the values as dates, parsed with RowSortKey.DateLayout
+	DateComparison RowComparison = 4
+
+	// Compare the values with RowSortKey.CompareFunc
+	CustomComparison RowComparison = 5
+)
+
+// Suffix of a row sort key introducing the layout of a date comparison, see ParseRowSortKey
+var dateComparisonRegex = regexp.MustCompile(`(?i):date=`)
+
+// A key the rows of the table are sorted by, see Config.SortRows.
+// Empty values sort last whatever the direction and comparison, they are never parsed nor passed to CompareFunc.
+type RowSortKey struct {
+	// Column the rows are sorted by, by header name. The column must be part of the table.
+	Column string
+
+	// Sort the rows in descending order
+	Descending bool
+
+	// How the values of the column are compared
+	Comparison RowComparison
+
+	// Layout of the dates for DateComparison, see https://pkg.go.dev/time#pkg-constants. Defaults to time.DateOnly (2006-01-02).
+	DateLayout string
+
+	// Comparison function for CustomComparison, returning a negative number if a sorts before b,
+	// a positive number if a sorts after b and 0 if they are equal. Only called with non-empty values.
+	CompareFunc func(a string, b string) int
+}
+
+// A value of a sort key, parsed according to the comparison of the key
+type rowSortValue struct {
+	text   string
+	number float64
+	date   time.Time
+
+	// empty values sort last, whatever the direction
+	empty bool
+}
+
+// Validate a row sort key. idx is the position of the key in SortRows.
+func validateRowSortKey(key RowSortKey, idx int) error {
+	if key.Column == "" {
+		return configErrorf("SortRows", "row sort key %d has no column", idx)
+	}
+
+	if key.Comparison < StringComparison || key.Comparison > CustomComparison {
+		return configErrorf("SortRows", "comparison of row sort key %q is out of range, please choose in range [0-5]", key.Column)
+	}
+
+	if key.Comparison == CustomComparison && key.CompareFunc == nil {
+		return configErrorf("SortRows", "comparison of row sort key %q is set to Custom but CompareFunc was not set", key.Column)
+	}
+
+	return nil
+}
+
+// Sort the rows of the table by the keys, in order of precedence. Rows with equal keys keep their order.
+// An error is returned if a key does not refer to a single column of the table, or a value cannot be parsed.
+// rowLines holds the line of the CSV each row was read from, to report the values that cannot be parsed.
+func sortRows(table *Table, keys []RowSortKey, rowLines []int) error {
+	headerNames := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		headerNames[i] = column.headerName()
+	}
+
+	// parse the values of every key once, rather than in every comparison
+	values := make([][]rowSortValue, len(keys))
+
+	for keyIdx, key := range keys {
+		colIndices := findColumnIndices(key.Column, headerNames)
+
+		switch {
+		case len(colIndices) == 0:
+			return configErrorf("SortRows", "column %q of SortRows is not part of the table", key.Column)
+		case len(colIndices) > 1:
+			return configErrorf("SortRows", "column %q of SortRows is ambiguous, refer to a single column with %s#n", key.Column, key.Column)
+		}
+
+		values[keyIdx] = make([]rowSortValue, len(table.Rows))
+
+		for rowIdx, row := range table.Rows {
+			value, err := parseRowSortValue(row[colIndices[0]], key)
+
+			if err != nil {
+				return &SortError{Line: rowLines[rowIdx], Column: colIndices[0] + 1, ColumnName: key.Column, Value: row[colIndices[0]], Err: err}
+			}
+
+			values[keyIdx][rowIdx] = value
+		}
+	}
+
+	order := make([]int, len(table.Rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		for keyIdx, key := range keys {
+			if res := compareRowSortValues(values[keyIdx][a], values[keyIdx][b], key); res != 0 {
+				return res
+			}
+		}
+
+		return 0
+	})
+
+	sorted := make([][]string, len(table.Rows))
+	for i, rowIdx := range order {
+		sorted[i] = table.Rows[rowIdx]
+	}
+
+	table.Rows = sorted
+
+	return nil
+}
+
+// Parse a value according to the comparison of the key
+func parseRowSortValue(val string, key RowSortKey) (rowSortValue, error) {
+	trimmed := strings.TrimSpace(val)
+
+	if trimmed == "" {
+		return rowSortValue{empty: true}, nil
+	}
+
+	value := rowSortValue{text: val}
+
+	switch key.Comparison {
+	case NumericComparison:
+		number, ok := parseNumericValue(trimmed)
+
+		if !ok {
+			return value, fmt.Errorf("%q is not a number", val)
+		}
+
+		value.number = number
+	case DateComparison:
+		layout := key.DateLayout
+		if layout == "" {
+			layout = time.DateOnly
+		}
+
+		date, err := time.Parse(layout, trimmed)
+
+		if err != nil {
+			return value, fmt.Errorf("%q is not a date of layout %q", val, layout)
+		}
+
+		value.date = date
+	}
+
+	return value, nil
+}
+
+// Compare two values of a key, in the direction of the key. Empty values sort last.
+func compareRowSortValues(a rowSortValue, b rowSortValue, key RowSortKey) int {
+	switch {
+	case a.empty && b.empty:
+		return 0
+	case a.empty:
+		return 1
+	case b.empty:
+		return -1
+	}
+
+	var res int
+
+	switch key.Comparison {
+	case CaseInsensitiveComparison:
+		res = strings.Compare(strings.ToLower(a.text), strings.ToLower(b.text))
+	case NaturalComparison:
+		res = compareNatural(a.text, b.text)
+	case NumericComparison:
+		res = cmp.Compare(a.number, b.number)
+	case DateComparison:
+		res = a.date.Compare(b.date)
+	case CustomComparison:
+		res = key.CompareFunc(a.text, b.text)
+	default:
+		res = strings.Compare(a.text, b.text)
+	}
+
+	if key.Descending {
+		return -res
+	}
+
+	return res
+}
+
+// Compare two strings chunk by chunk, runs of digits being compared by their numeric value
+func compareNatural(a string, b string) int {
+	for a != "" && b != "" {
+		chunkA, restA := cutNaturalChunk(a)
+		chunkB, restB := cutNaturalChunk(b)
+
+		if res := compareNaturalChunks(chunkA, chunkB); res != 0 {
+			return res
+		}
+
+		a, b = restA, restB
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+// Cut the leading run of digits, or of other characters, off the string
+func cutNaturalChunk(val string) (string, string) {
+	digits := isASCIIDigit(rune(val[0]))
+
+	end := strings.IndexFunc(val, func(r rune) bool {
+		return isASCIIDigit(r) != digits
+	})
+
+	if end < 0 {
+		return val, ""
+	}
+
+	return val[:end], val[end:]
+}
+
+// Compare two chunks of a natural comparison. Runs of digits are compared by their numeric value,
+// falling back to the number of leading zeros.
+func compareNaturalChunks(a string, b string) int {
+	if !isASCIIDigit(rune(a[0])) || !isASCIIDigit(rune(b[0])) {
+		return strings.Compare(a, b)
+	}
+
+	trimmedA := strings.TrimLeft(a, "0")
+	trimmedB := strings.TrimLeft(b, "0")
+
+	// the longer number is the greater one, numbers of the same length compare like strings
+	if res := cmp.Compare(len(trimmedA), len(trimmedB)); res != 0 {
+		return res
+	}
+
+	if res := strings.Compare(trimmedA, trimmedB); res != 0 {
+		return res
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// Parse a row sort key of the form column[:asc|desc][:comparison], where the comparison is one of
+// string, nocase, natural, numeric, date (or date=layout). Column names and date layouts may contain colons.
+func ParseRowSortKey(val string) (RowSortKey, error) {
+	spec := val
+	key := RowSortKey{}
+
+	// everything after date= is the layout, which may contain colons itself, e.g. date=2006-01-02 15:04
+	if match := dateComparisonRegex.FindAllStringIndex(spec, -1); match != nil {
+		idx := match[len(match)-1]
+		key.Comparison = DateComparison
+		key.DateLayout = spec[idx[1]:]
+		spec = spec[:idx[0]]
+	}
+
+	parts := strings.Split(spec, ":")
+
+	if len(parts) > 1 && key.Comparison != DateComparison {
+		if comparison, layout, ok := parseRowComparison(parts[len(parts)-1]); ok {
+			key.Comparison = comparison
+			key.DateLayout = layout
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	if len(parts) > 1 {
+		switch strings.ToLower(parts[len(parts)-1]) {
+		case "asc", "ascending":
+			parts = parts[:len(parts)-1]
+		case "desc", "descending":
+			key.Descending = true
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	key.Column = strings.Join(parts, ":")
+
+	if key.Column == "" {
+		return key, errors.New("row sort key " + val + " has no column")
+	}
+
+	return key, nil
+}
+
+// Parse the name of a row comparison. The layout of dates follows an equal sign, e.g. date=02/01/2006.
+func parseRowComparison(val string) (RowComparison, string, bool) {
+	name, layout, _ := strings.Cut(val, "=")
+
+	switch strings.ToLower(name) {
+	case "string":
+		return StringComparison, "", true
+	case "nocase":
+		return CaseInsensitiveComparison, "", true
+	case "natural":
+		return NaturalComparison, "", true
+	case "numeric":
+		return NumericComparison, "", true
+	case "date":
+		return DateComparison, layout, true
+	}
+
+	return StringComparison, "", false
+}
@@ -0,0 +1,466 @@
+package csv2mdtable
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// Convert CSV data read from r into a markdown table written to w.
+// Records are read and written one at a time, so the whole input is never held in memory.
+// The beautified table needs the width of every column up front, which is determined either by
+// reading the input twice (when r can seek), by spooling the records to a temporary file or,
+// if StreamWidthSampleRows is set, by sampling the first rows of the input.
+// Custom renderers that cannot write a table row by row receive the whole table instead, and so do all renderers
+// when the rows are sorted.
+// Unlike Convert, the written table ends with a new line character.
+// Returns the errors described at Parse, except that nothing is written if every column is excluded.
+func ConvertReader(r io.Reader, w io.Writer, cfg Config) error {
+	_, err := ConvertReaderWithReport(r, w, cfg)
+	return err
+}
+
+// Convert CSV data read from r into a markdown table written to w, like ConvertReader.
+// Also returns the report of the malformed records that were repaired or skipped in lenient mode (see Config.Lenient),
+// which is returned along with the error if the conversion fails.
+func ConvertReaderWithReport(r io.Reader, w io.Writer, cfg Config) (Report, error) {
+	report := &Report{}
+
+	rowFilter, cfgErr := validateConfig(cfg)
+
+	if cfgErr != nil {
+		return *report, fmt.Errorf("Configuration error: %w\n", cfgErr)
+	}
+
+	err := convertReader(r, w, cfg, rowFilter, report)
+	return *report, err
+}
+
+// Convert CSV data read from r into a markdown table written to w, adding the warnings of lenient mode
+// to the report. The config must already be validated, rowFilter is its parsed RowFilterExpression.
+func convertReader(r io.Reader, w io.Writer, cfg Config, rowFilter filterNode, report *Report) error {
+	start := time.Now()
+	logger := loggerFor(cfg)
+
+	bufferedWriter := bufio.NewWriter(w)
+
+	var err error
+
+	baseRenderer := rendererFor(cfg)
+	renderer, streamable := baseRenderer.(rowRenderer)
+
+	// nil for renderers that do not pad their cells
+	measurer, _ := renderer.(columnMeasurer)
+
+	rendererName := fmt.Sprintf("%T", baseRenderer)
+
+	switch {
+	case !streamable || len(cfg.SortRows) > 0:
+		logger.Debug("Collecting every row in memory", "renderer", rendererName)
+		err = streamCollected(r, bufferedWriter, cfg, rowFilter, report)
+	case measurer == nil && !cfg.AutoAlign:
+		logger.Debug("Streaming rows in a single pass", "renderer", rendererName)
+		err = streamSinglePass(r, bufferedWriter, renderer, cfg, rowFilter, report)
+	case cfg.StreamWidthSampleRows > 0:
+		logger.Debug("Streaming rows with column widths sampled from the first rows", "renderer", rendererName, "sample_rows", cfg.StreamWidthSampleRows)
+		err = streamSampled(r, bufferedWriter, renderer, measurer, cfg, rowFilter, report)
+	default:
+		logger.Debug("Streaming rows in two passes", "renderer", rendererName)
+		err = streamTwoPass(r, bufferedWriter, renderer, measurer, cfg, rowFilter, report)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Converted CSV", "warnings", len(report.Warnings), "duration", time.Since(start))
+
+	return bufferedWriter.Flush()
+}
+
+// Convert the CSV file at path into a markdown table written to w. See ConvertReader.
+func ConvertFile(path string, w io.Writer, cfg Config) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return ConvertReader(file, w, cfg)
+}
+
+// Read the header line and create the table without rows. Also returns the indices of the
+// header line's columns that make up the table's columns.
+// The returned table is nil when every column was excluded and there is nothing to write.
+func readStreamHead(csvReader *recordReader, cfg Config, rowFilter filterNode) (*Table, []int, error) {
+	headerLine, err := csvReader.Read()
+
+	if err == io.EOF {
+		return nil, nil, ErrEmptyInput
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.HasHeader != FirstRecordHeader {
+		// the record may be reused by the CSV reader (see ReuseRecord)
+		csvReader.pending = slices.Clone(headerLine)
+	}
+
+	if headerLine, err = resolveHeaderLine(headerLine, cfg); err != nil {
+		return nil, nil, fmt.Errorf("Configuration error: %w", err)
+	}
+
+	table, columnIndices, err := newTable(headerLine, cfg, rowFilter)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Configuration error: %w", err)
+	}
+
+	if len(table.Columns) == 0 {
+		loggerFor(cfg).Warn("All columns were excluded from conversion. Writing nothing")
+		return nil, nil, nil
+	}
+
+	return table, columnIndices, nil
+}
+
+// Read the next record kept by the row filter and pick the values of the table's columns out of it.
+// Returns io.EOF once the input is exhausted.
+func readStreamRow(csvReader *recordReader, table *Table, columnIndices []int) ([]string, error) {
+	for {
+		record, err := csvReader.Read()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if table.keepRecord == nil || table.keepRecord(record) {
+			return projectRecord(record, table.Columns, columnIndices), nil
+		}
+	}
+}
+
+// Create an alignment detector for the measuring pass, or nil if AutoAlign is not set
+func newStreamAlignmentDetector(table *Table, cfg Config) *alignmentDetector {
+	if !cfg.AutoAlign {
+		return nil
+	}
+
+	return newAlignmentDetector(len(table.Columns))
+}
+
+// Write every remaining record of the reader as a data row, followed by the tail of the table
+func streamRows(csvReader *recordReader, columnIndices []int, w io.Writer, renderer rowRenderer, table *Table, maxLenOfCol []int, firstRowIdx int) error {
+	for rowIdx := firstRowIdx; ; rowIdx++ {
+		row, err := readStreamRow(csvReader, table, columnIndices)
+
+		if err == io.EOF {
+			return renderer.writeTail(w, table, maxLenOfCol)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := renderer.writeRow(w, table, row, maxLenOfCol, rowIdx); err != nil {
+			return err
+		}
+	}
+}
+
+// Collect every row in memory and hand the whole table to the renderer
+func streamCollected(r io.Reader, w io.Writer, cfg Config, rowFilter filterNode, report *Report) error {
+	csvReader := newRecordReader(cfg, r, report)
+
+	table, columnIndices, err := readStreamHead(csvReader, cfg, rowFilter)
+
+	if table == nil {
+		return err
+	}
+
+	var rowLines []int
+
+	for {
+		row, err := readStreamRow(csvReader, table, columnIndices)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		updateColumnWidths(table.Columns, row, cfg.WidthMode)
+		table.Rows = append(table.Rows, row)
+		rowLines = append(rowLines, csvReader.line)
+	}
+
+	if len(cfg.SortRows) > 0 {
+		if err := sortRows(table, cfg.SortRows, rowLines); err != nil {
+			return err
+		}
+	}
+
+	if cfg.AutoAlign {
+		detectColumnAlignments(table, columnIndices, cfg)
+	}
+
+	return rendererFor(cfg).Render(w, table)
+}
+
+// Write the rows as soon as they are read, for renderers that do not pad their cells.
+// Not used with AutoAlign, which needs to see every row before the head can be written.
+func streamSinglePass(r io.Reader, w io.Writer, renderer rowRenderer, cfg Config, rowFilter filterNode, report *Report) error {
+	csvReader := newRecordReader(cfg, r, report)
+
+	table, columnIndices, err := readStreamHead(csvReader, cfg, rowFilter)
+
+	if table == nil {
+		return err
+	}
+
+	if err := renderer.writeHead(w, table, nil); err != nil {
+		return err
+	}
+
+	return streamRows(csvReader, columnIndices, w, renderer, table, nil, 1)
+}
+
+// Determine the column widths, and the alignments of AutoAlign, from the first StreamWidthSampleRows rows only.
+// Values in later rows that are wider than their column are written unpadded.
+func streamSampled(r io.Reader, w io.Writer, renderer rowRenderer, measurer columnMeasurer, cfg Config, rowFilter filterNode, report *Report) error {
+	csvReader := newRecordReader(cfg, r, report)
+
+	table, columnIndices, err := readStreamHead(csvReader, cfg, rowFilter)
+
+	if table == nil {
+		return err
+	}
+
+	for len(table.Rows) < cfg.StreamWidthSampleRows {
+		row, err := readStreamRow(csvReader, table, columnIndices)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	if cfg.AutoAlign {
+		detectColumnAlignments(table, columnIndices, cfg)
+	}
+
+	var maxLenOfCol []int
+	if measurer != nil {
+		maxLenOfCol = getMaxColumnLengths(table, measurer)
+	}
+
+	loggerFor(cfg).Debug("Measured column widths", "column_widths", maxLenOfCol)
+
+	if err := renderer.writeHead(w, table, maxLenOfCol); err != nil {
+		return err
+	}
+
+	for idx, row := range table.Rows {
+		if err := renderer.writeRow(w, table, row, maxLenOfCol, idx+1); err != nil {
+			return err
+		}
+	}
+
+	return streamRows(csvReader, columnIndices, w, renderer, table, maxLenOfCol, len(table.Rows)+1)
+}
+
+// Measure every row (and detect the alignments, see AutoAlign) in a first pass and write the table in a second pass.
+// The second pass re-reads r if it can seek, otherwise the rows are spooled to a temporary file.
+func streamTwoPass(r io.Reader, w io.Writer, renderer rowRenderer, measurer columnMeasurer, cfg Config, rowFilter filterNode, report *Report) error {
+	if seeker, ok := r.(io.Seeker); ok {
+		// pipes such as stdin implement io.Seeker but fail when seeking
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return streamReread(r, seeker, start, w, renderer, measurer, cfg, rowFilter, report)
+		}
+	}
+
+	return streamSpooled(r, w, renderer, measurer, cfg, rowFilter, report)
+}
+
+// Measure the column widths, then seek back to start and convert the input again
+func streamReread(r io.Reader, seeker io.Seeker, start int64, w io.Writer, renderer rowRenderer, measurer columnMeasurer, cfg Config, rowFilter filterNode, report *Report) error {
+	csvReader := newRecordReader(cfg, r, report)
+
+	table, columnIndices, err := readStreamHead(csvReader, cfg, rowFilter)
+
+	if table == nil {
+		return err
+	}
+
+	var maxLenOfCol []int
+	if measurer != nil {
+		maxLenOfCol = measureColumnLengths(table, measurer)
+	}
+
+	detector := newStreamAlignmentDetector(table, cfg)
+
+	for {
+		row, err := readStreamRow(csvReader, table, columnIndices)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if measurer != nil {
+			updateMaxColumnLengths(maxLenOfCol, row, measurer)
+		}
+
+		if detector != nil {
+			detector.observe(row)
+		}
+	}
+
+	if detector != nil {
+		alignColumns(table.Columns, columnIndices, cfg, detector)
+	}
+
+	if measurer != nil {
+		applyMinimumColumnLengths(maxLenOfCol, table.Columns, measurer)
+	}
+
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	// the warnings of the records were already reported in the first pass
+	csvReader = newRecordReader(cfg, r, &Report{})
+
+	// skip the header line, it was already read in the first pass
+	if cfg.HasHeader == FirstRecordHeader {
+		if _, err := csvReader.Read(); err != nil {
+			return err
+		}
+	}
+
+	loggerFor(cfg).Debug("Measured column widths", "column_widths", maxLenOfCol)
+
+	if err := renderer.writeHead(w, table, maxLenOfCol); err != nil {
+		return err
+	}
+
+	return streamRows(csvReader, columnIndices, w, renderer, table, maxLenOfCol, 1)
+}
+
+// Measure the column widths while copying the rows to a temporary file, then convert the copy
+func streamSpooled(r io.Reader, w io.Writer, renderer rowRenderer, measurer columnMeasurer, cfg Config, rowFilter filterNode, report *Report) error {
+	csvReader := newRecordReader(cfg, r, report)
+
+	table, columnIndices, err := readStreamHead(csvReader, cfg, rowFilter)
+
+	if table == nil {
+		return err
+	}
+
+	spoolFile, err := os.CreateTemp("", "csv2mdtable-*.csv")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(spoolFile.Name())
+	defer spoolFile.Close()
+
+	spoolWriter := csv.NewWriter(spoolFile)
+
+	var maxLenOfCol []int
+	if measurer != nil {
+		maxLenOfCol = measureColumnLengths(table, measurer)
+	}
+
+	detector := newStreamAlignmentDetector(table, cfg)
+
+	for {
+		row, err := readStreamRow(csvReader, table, columnIndices)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if measurer != nil {
+			updateMaxColumnLengths(maxLenOfCol, row, measurer)
+		}
+
+		if detector != nil {
+			detector.observe(row)
+		}
+
+		// a leading empty field keeps a row with a single empty value from being written as
+		// an empty line, which would be skipped when the spool file is read back
+		if err := spoolWriter.Write(append([]string{""}, row...)); err != nil {
+			return err
+		}
+	}
+
+	spoolWriter.Flush()
+
+	if err := spoolWriter.Error(); err != nil {
+		return err
+	}
+
+	if detector != nil {
+		alignColumns(table.Columns, columnIndices, cfg, detector)
+	}
+
+	if measurer != nil {
+		applyMinimumColumnLengths(maxLenOfCol, table.Columns, measurer)
+	}
+
+	if _, err := spoolFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	loggerFor(cfg).Debug("Measured column widths", "column_widths", maxLenOfCol)
+
+	if err := renderer.writeHead(w, table, maxLenOfCol); err != nil {
+		return err
+	}
+
+	// rows in the spool file are already validated and in the order of the table's columns
+	spoolReader := csv.NewReader(bufio.NewReader(spoolFile))
+	spoolReader.FieldsPerRecord = -1
+	spoolReader.ReuseRecord = true
+
+	for rowIdx := 1; ; rowIdx++ {
+		record, err := spoolReader.Read()
+
+		if err == io.EOF {
+			return renderer.writeTail(w, table, maxLenOfCol)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := renderer.writeRow(w, table, record[1:], maxLenOfCol, rowIdx); err != nil {
+			return err
+		}
+	}
+}
@@ -2,74 +2,74 @@ package csv2mdtable
 
 import (
 	"errors"
-	"unicode/utf8"
+	"strings"
 )
 
 const padLengthErrorString = "the length of the original string already exceeded desired length"
 
 // pad characters to start of a string
-func padStart(originalString string, desiredLen int, paddingChar rune) (string, error) {
-	if utf8.RuneCountInString(originalString) > desiredLen {
+func padStart(originalString string, desiredLen int, paddingChar rune, mode WidthMode) (string, error) {
+	if stringWidth(originalString, mode) > desiredLen {
 		return "", errors.New(padLengthErrorString)
 	}
 
-	lenDiff := desiredLen - utf8.RuneCountInString(originalString)
+	lenDiff := desiredLen - stringWidth(originalString, mode)
 
 	if lenDiff == 0 {
 		return originalString, nil
 	}
 
-	preFix := ""
-
-	for range lenDiff {
-		preFix += string(paddingChar)
-	}
-
-	return preFix + originalString, nil
+	return strings.Repeat(string(paddingChar), lenDiff) + originalString, nil
 }
 
 // pad characters to the end of a string
-func padEnd(originalString string, desiredLen int, paddingChar rune) (string, error) {
-	if utf8.RuneCountInString(originalString) > desiredLen {
+func padEnd(originalString string, desiredLen int, paddingChar rune, mode WidthMode) (string, error) {
+	if stringWidth(originalString, mode) > desiredLen {
 		return "", errors.New(padLengthErrorString)
 	}
 
-	lenDiff := desiredLen - utf8.RuneCountInString(originalString)
+	lenDiff := desiredLen - stringWidth(originalString, mode)
 
 	if lenDiff == 0 {
 		return originalString, nil
 	}
 
-	postFix := ""
-
-	for range lenDiff {
-		postFix += string(paddingChar)
-	}
-
-	return originalString + postFix, nil
+	return originalString + strings.Repeat(string(paddingChar), lenDiff), nil
 }
 
 // Pad both sides. If odd characters are to be padded, the longer string is padded to the start of the string.
-func padCenter(originalString string, desiredLen int, paddingChar rune) (string, error) {
-	if utf8.RuneCountInString(originalString) > desiredLen {
+func padCenter(originalString string, desiredLen int, paddingChar rune, mode WidthMode) (string, error) {
+	if stringWidth(originalString, mode) > desiredLen {
 		return "", errors.New(padLengthErrorString)
 	}
 
-	lenDiff := desiredLen - utf8.RuneCountInString(originalString)
+	lenDiff := desiredLen - stringWidth(originalString, mode)
 
 	toPadStart := lenDiff / 2
 	toPadEnd := lenDiff - toPadStart
 
 	resStr := originalString
-	resStr, err := padEnd(originalString, utf8.RuneCountInString(resStr)+toPadEnd, paddingChar)
+	resStr, err := padEnd(originalString, stringWidth(resStr, mode)+toPadEnd, paddingChar, mode)
 	if err != nil {
 		return "", err
 	}
 
-	resStr, err = padStart(resStr, utf8.RuneCountInString(resStr)+toPadStart, paddingChar)
+	resStr, err = padStart(resStr, stringWidth(resStr, mode)+toPadStart, paddingChar, mode)
 	if err != nil {
 		return "", err
 	}
 
 	return resStr, nil
 }
+
+// Pad a string to the desired length, on the sides given by the alignment
+func padAligned(originalString string, desiredLen int, align Align, mode WidthMode) (string, error) {
+	switch align {
+	case Left:
+		return padEnd(originalString, desiredLen, ' ', mode)
+	case Right:
+		return padStart(originalString, desiredLen, ' ', mode)
+	}
+
+	return padCenter(originalString, desiredLen, ' ', mode)
+}
@@ -0,0 +1,435 @@
+package csv2mdtable
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A column of a parsed table
+type Column struct {
+	// Name of the column, as found in the header line
+	Name string
+
+	// Alignment of the column's content
+	Align Align
+
+	// Width of the widest value in the column, header included, measured according to Config.WidthMode
+	Width int
+
+	// Name of the column in the CSV header line, if Name was replaced by an alias (see Config.ColumnAliases)
+	header string
+
+	// Indices of the header line's columns merged into this column (see MergeDuplicateHeaders)
+	merged []int
+}
+
+// Separator of the values of merged columns, see MergeDuplicateHeaders, and of the values folded into the overflow column
+const mergedValuesSeparator = "; "
+
+// Name of the column holding the extra values of long records, see OverflowRaggedRows
+const overflowColumnName = "Overflow"
+
+// Table is the intermediate model between parsing the CSV and rendering it.
+// Excluded columns are already left out and the columns are in their final order.
+// Values are stored as they were read, renderers escape them as needed by their output syntax.
+type Table struct {
+	// Caption of the table
+	Caption string
+
+	// Columns of the table, in the order they should be rendered
+	Columns []Column
+
+	// Data rows of the table. Each row holds one value per column, in the order of Columns.
+	Rows [][]string
+
+	// Decides which records of the CSV become rows of the table (see Config.RowFilter), nil keeps every record
+	keepRecord func(record []string) bool
+}
+
+// Get the name the config refers to the column by, which is its name in the CSV header line
+func (c Column) headerName() string {
+	if c.header != "" {
+		return c.header
+	}
+
+	return c.Name
+}
+
+// Get the width of each column, see Column.Width
+func (t *Table) columnWidths() []int {
+	widths := make([]int, len(t.Columns))
+	for i, column := range t.Columns {
+		widths[i] = column.Width
+	}
+	return widths
+}
+
+// Get the names of the columns, which make up the header line of the table
+func (t *Table) Header() []string {
+	header := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		header[i] = column.Name
+	}
+	return header
+}
+
+// Parse CSV string into a Table, applying the column exclusion, row filter, sorting (of columns and rows) and alignment of the config.
+// Returns ErrAllColumnsExcluded if every column is excluded, ErrEmptyInput if the CSV has no records,
+// a *ParseError if it cannot be read and a *ConfigError if the config is invalid or does not fit the CSV.
+func Parse(csv string, cfg Config) (*Table, error) {
+	return parse(csv, cfg, &Report{})
+}
+
+// Parse CSV string into a Table, like Parse. Also returns the report of the malformed records that were
+// repaired or skipped in lenient mode (see Config.Lenient), which is returned along with the error if parsing fails.
+func ParseWithReport(csv string, cfg Config) (*Table, Report, error) {
+	report := &Report{}
+	table, err := parse(csv, cfg, report)
+	return table, *report, err
+}
+
+// Parse CSV string into a Table, adding the warnings of lenient mode to the report
+func parse(csv string, cfg Config, report *Report) (*Table, error) {
+	rowFilter, err := validateInput(csv, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRecords(newRecordReader(cfg, strings.NewReader(csv), report), cfg, rowFilter, report)
+}
+
+// Make sure the CSV string is not empty and the config is valid, before reading the CSV.
+// Also returns the parsed RowFilterExpression of the config, see validateConfig.
+func validateInput(csv string, cfg Config) (filterNode, error) {
+	if csv == "" {
+		return nil, ErrEmptyInput
+	}
+
+	rowFilter, cfgErr := validateConfig(cfg)
+	if cfgErr != nil {
+		return nil, fmt.Errorf("Configuration error: %w\n", cfgErr)
+	}
+
+	return rowFilter, nil
+}
+
+// Parse the records of the reader into a Table. The config must already be validated, rowFilter is its parsed RowFilterExpression.
+func parseRecords(csvReader *recordReader, cfg Config, rowFilter filterNode, report *Report) (*Table, error) {
+	start := time.Now()
+
+	records, lines, readErr := csvReader.ReadAll()
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if len(records) == 0 {
+		return nil, ErrEmptyInput
+	}
+
+	dataRecords, dataLines := records[1:], lines[1:]
+	if cfg.HasHeader != FirstRecordHeader {
+		dataRecords, dataLines = records, lines
+	}
+
+	headerLine, err := resolveHeaderLine(records[0], cfg)
+
+	if err != nil {
+		return nil, fmt.Errorf("Configuration error: %w\n", err)
+	}
+
+	table, columnIndices, err := newTable(headerLine, cfg, rowFilter)
+
+	if err != nil {
+		return nil, fmt.Errorf("Configuration error: %w\n", err)
+	}
+
+	if len(table.Columns) == 0 {
+		return nil, ErrAllColumnsExcluded
+	}
+
+	table.Rows = make([][]string, 0, len(dataRecords))
+	var rowLines []int
+	for i, record := range dataRecords {
+		if table.keepRecord != nil && !table.keepRecord(record) {
+			continue
+		}
+
+		row := projectRecord(record, table.Columns, columnIndices)
+		updateColumnWidths(table.Columns, row, cfg.WidthMode)
+		table.Rows = append(table.Rows, row)
+		rowLines = append(rowLines, dataLines[i])
+	}
+
+	if len(cfg.SortRows) > 0 {
+		if err := sortRows(table, cfg.SortRows, rowLines); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.AutoAlign {
+		detectColumnAlignments(table, columnIndices, cfg)
+	}
+
+	loggerFor(cfg).Debug("Parsed CSV",
+		"rows", len(table.Rows),
+		"filtered_rows", len(dataRecords)-len(table.Rows),
+		"warnings", len(report.Warnings),
+		"column_widths", table.columnWidths(),
+		"duration", time.Since(start))
+
+	return table, nil
+}
+
+// Create a table without rows from the header line. Also returns the indices of the
+// header line's columns that make up the table's columns, in order.
+// rowFilter is the parsed RowFilterExpression of the config, nil if there is none.
+func newTable(headerLine []string, cfg Config, rowFilter filterNode) (*Table, []int, error) {
+	headerLine, merged, err := resolveDuplicateHeaders(headerLine, cfg.DuplicateHeaders)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excludedColumnsIndices := getIndicesOfExcludedColumns(cfg.ExcludedColumns, headerLine)
+	excludedColumnsIndices = append(excludedColumnsIndices, cfg.ExcludedColumnIndices...)
+
+	// the merged columns are read along with the column they are merged into
+	for _, mergedIndices := range merged {
+		excludedColumnsIndices = append(excludedColumnsIndices, mergedIndices...)
+	}
+
+	orderedColumnsIndices, err := orderColumnIndices(cfg, headerLine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aliases := resolveColumnAliases(cfg.ColumnAliases, headerLine)
+
+	table := &Table{Caption: cfg.Caption}
+	var columnIndices []int
+
+	for _, i := range orderedColumnsIndices {
+		// If current column is excluded, ignore it
+		if slices.Contains(excludedColumnsIndices, i) {
+			continue
+		}
+
+		column := Column{Name: headerLine[i], merged: merged[i]}
+
+		if alias, ok := aliases[i]; ok {
+			column.Name = alias
+			column.header = headerLine[i]
+		} else if cfg.HasHeader == EmptyHeader {
+			column.Name = ""
+			column.header = headerLine[i]
+		}
+
+		column.Width = stringWidth(column.Name, cfg.WidthMode)
+
+		columnIndices = append(columnIndices, i)
+		table.Columns = append(table.Columns, column)
+	}
+
+	alignColumns(table.Columns, columnIndices, cfg, nil)
+
+	var excludedColumns []string
+	for i, name := range headerLine {
+		if !slices.Contains(columnIndices, i) {
+			excludedColumns = append(excludedColumns, name)
+		}
+	}
+
+	loggerFor(cfg).Debug("Created table", "columns", table.Header(), "excluded_columns", excludedColumns)
+
+	table.keepRecord, err = newRecordFilter(cfg, rowFilter, headerLine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return table, columnIndices, nil
+}
+
+// Align the columns of the table based on the values of its rows, see AutoAlign
+func detectColumnAlignments(table *Table, columnIndices []int, cfg Config) {
+	detector := newAlignmentDetector(len(table.Columns))
+	for _, row := range table.Rows {
+		detector.observe(row)
+	}
+	alignColumns(table.Columns, columnIndices, cfg, detector)
+}
+
+// Pick the values of the given columns out of a record, in order
+func projectRecord(record []string, columns []Column, columnIndices []int) []string {
+	row := make([]string, len(columnIndices))
+	for i, colIdx := range columnIndices {
+		row[i] = record[colIdx]
+
+		for _, mergedIdx := range columns[i].merged {
+			switch {
+			case record[mergedIdx] == "":
+			case row[i] == "":
+				row[i] = record[mergedIdx]
+			default:
+				row[i] += mergedValuesSeparator + record[mergedIdx]
+			}
+		}
+	}
+	return row
+}
+
+// Grow the width of each column to fit the values of the row
+func updateColumnWidths(columns []Column, row []string, mode WidthMode) {
+	for i, val := range row {
+		columns[i].Width = max(columns[i].Width, stringWidth(val, mode))
+	}
+}
+
+// Get the header line from the first record of the CSV: the record itself or, for a CSV without
+// a header line (see HasHeader), the generated names. The overflow column of OverflowRaggedRows is named Overflow.
+func resolveHeaderLine(firstRecord []string, cfg Config) ([]string, error) {
+	fieldCount := len(firstRecord)
+	if cfg.RaggedRows == OverflowRaggedRows {
+		fieldCount--
+	}
+
+	// the record may be reused by the CSV reader (see ReuseRecord)
+	headerLine := slices.Clone(firstRecord[:fieldCount])
+
+	if cfg.HasHeader != FirstRecordHeader {
+		var err error
+		if headerLine, err = generateHeaderLine(fieldCount, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.RaggedRows == OverflowRaggedRows {
+		headerLine = append(headerLine, overflowColumnName)
+	}
+
+	return headerLine, nil
+}
+
+// Generate the header line of a CSV without one (see HasHeader), from the number of fields of its first record
+func generateHeaderLine(fieldCount int, cfg Config) ([]string, error) {
+	if len(cfg.HeaderNames) > fieldCount {
+		return nil, configErrorf("HeaderNames", "%d HeaderNames were given but the CSV has %d columns", len(cfg.HeaderNames), fieldCount)
+	}
+
+	headerLine := make([]string, fieldCount)
+	copy(headerLine, cfg.HeaderNames)
+
+	for i := len(cfg.HeaderNames); i < fieldCount; i++ {
+		if cfg.HasHeader == LetteredHeader {
+			headerLine[i] = spreadsheetColumnName(i)
+		} else {
+			headerLine[i] = "Column " + strconv.Itoa(i+1)
+		}
+	}
+
+	return headerLine, nil
+}
+
+// Get the name of the column of a spreadsheet at the index (starting at 0): A, B, ..., Z, AA, AB...
+func spreadsheetColumnName(idx int) string {
+	var name []byte
+	for n := idx + 1; n > 0; n = (n - 1) / 26 {
+		name = append([]byte{byte('A' + (n-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// Get the indices of columns that are excluded in config
+func getIndicesOfExcludedColumns(excludedColumns []string, headerLine []string) []int {
+	var excludedColumnsIndices []int
+	for _, name := range excludedColumns {
+		excludedColumnsIndices = append(excludedColumnsIndices, findColumnIndices(name, headerLine)...)
+	}
+	return excludedColumnsIndices
+}
+
+// Get the indices of the columns a name refers to: every column with this header name or,
+// for a name of the form name#n, the n-th column with the header name (starting at 1)
+func findColumnIndices(name string, headerLine []string) []int {
+	var indices []int
+	for i, header := range headerLine {
+		if header == name {
+			indices = append(indices, i)
+		}
+	}
+
+	if len(indices) > 0 {
+		return indices
+	}
+
+	if idx := strings.LastIndex(name, "#"); idx >= 0 {
+		occurrence, err := strconv.Atoi(name[idx+1:])
+		occurrences := findColumnIndices(name[:idx], headerLine)
+
+		if err == nil && occurrence >= 1 && occurrence <= len(occurrences) {
+			return []int{occurrences[occurrence-1]}
+		}
+	}
+
+	return nil
+}
+
+// Get the alias of every column that has one, by index of the column in the header line.
+// Aliases given as name#n take precedence over the aliases of all columns with the header name.
+func resolveColumnAliases(columnAliases map[string]string, headerLine []string) map[int]string {
+	aliases := map[int]string{}
+
+	// name sorts before name#n
+	for _, name := range slices.Sorted(maps.Keys(columnAliases)) {
+		for _, i := range findColumnIndices(name, headerLine) {
+			aliases[i] = columnAliases[name]
+		}
+	}
+
+	return aliases
+}
+
+// Apply the duplicate header policy to the header line. Returns the header line to use and, for
+// MergeDuplicateHeaders, the indices of the columns merged into the first column with the same name.
+func resolveDuplicateHeaders(headerLine []string, policy DuplicateHeaderPolicy) ([]string, map[int][]int, error) {
+	merged := map[int][]int{}
+
+	if policy == KeepDuplicateHeaders {
+		return headerLine, merged, nil
+	}
+
+	// index of the first column with each name
+	firstIndices := map[string]int{}
+	resolved := slices.Clone(headerLine)
+
+	for i, name := range headerLine {
+		firstIdx, duplicate := firstIndices[name]
+
+		if !duplicate {
+			firstIndices[name] = i
+			continue
+		}
+
+		switch policy {
+		case ErrorOnDuplicateHeaders:
+			return nil, nil, configErrorf("DuplicateHeaders", "columns %d and %d share the header name %q", firstIdx, i, name)
+		case SuffixDuplicateHeaders:
+			// skip the suffixes taken by other columns, e.g. a column already named Notes_2
+			for n := 2; ; n++ {
+				suffixed := name + "_" + strconv.Itoa(n)
+
+				if !slices.Contains(headerLine, suffixed) && !slices.Contains(resolved[:i], suffixed) {
+					resolved[i] = suffixed
+					break
+				}
+			}
+		case MergeDuplicateHeaders:
+			merged[firstIdx] = append(merged[firstIdx], i)
+		}
+	}
+
+	return resolved, merged, nil
+}
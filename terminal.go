@@ -0,0 +1,333 @@
+package csv2mdtable
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type BoxStyle int
+
+const (
+	// Draw the borders with Unicode box-drawing characters: ┌─┬─┐
+	UnicodeBoxStyle BoxStyle = 0
+
+	// Draw the borders with plain ASCII characters: +-+-+
+	ASCIIBoxStyle BoxStyle = 1
+)
+
+type CellOverflow int
+
+const (
+	// Wrap values that do not fit in their column onto several lines, breaking lines between words when possible
+	WrapCells CellOverflow = 0
+
+	// Cut values that do not fit in their column and end them with an ellipsis
+	TruncateCells CellOverflow = 1
+)
+
+// Renders a table for a terminal, with borders drawn around every cell. Values spanning several lines
+// keep their lines, the caption of the table is written above it.
+// Set MaxWidth (e.g. to TerminalWidth(os.Stdout)) to shrink the widest columns until the table fits.
+type TerminalTableRenderer struct {
+	// Characters the borders are drawn with
+	Style BoxStyle
+
+	// ANSI SGR parameters the header cells are displayed with, e.g. "1" for bold or "1;36" for bold cyan.
+	// No escape sequence is written if empty.
+	HeaderStyle string
+
+	// Maximum width of the table, borders included. 0 means no limit.
+	MaxWidth int
+
+	// What happens to values that do not fit in their column once the table is shrunk to MaxWidth
+	Overflow CellOverflow
+
+	// How the width of the values is measured to pad them
+	WidthMode WidthMode
+}
+
+// Get the width of the terminal the file (usually os.Stdout) is attached to, in columns.
+// Falls back to the COLUMNS environment variable when the size cannot be queried, and returns 0 if the width is unknown.
+func TerminalWidth(f *os.File) int {
+	if f != nil {
+		if columns := terminalColumns(f); columns > 0 {
+			return columns
+		}
+	}
+
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	return 0
+}
+
+// Characters the borders of a table are made of
+type boxChars struct {
+	horizontal, vertical                  string
+	topLeft, topMiddle, topRight          string
+	middleLeft, middleMiddle, middleRight string
+	bottomLeft, bottomMiddle, bottomRight string
+	ellipsis                              string
+}
+
+var unicodeBoxChars = boxChars{
+	horizontal: "─", vertical: "│",
+	topLeft: "┌", topMiddle: "┬", topRight: "┐",
+	middleLeft: "├", middleMiddle: "┼", middleRight: "┤",
+	bottomLeft: "└", bottomMiddle: "┴", bottomRight: "┘",
+	ellipsis: "…",
+}
+
+var asciiBoxChars = boxChars{
+	horizontal: "-", vertical: "|",
+	topLeft: "+", topMiddle: "+", topRight: "+",
+	middleLeft: "+", middleMiddle: "+", middleRight: "+",
+	bottomLeft: "+", bottomMiddle: "+", bottomRight: "+",
+	ellipsis: "...",
+}
+
+func (r TerminalTableRenderer) Render(w io.Writer, table *Table) error {
+	return renderRows(r, w, table, getMaxColumnLengths(table, r))
+}
+
+func (r TerminalTableRenderer) cellWidth(val string) int {
+	width := 0
+	for _, line := range splitCellLines(sanitizeTerminalCell(val)) {
+		width = max(width, stringWidth(line, r.WidthMode))
+	}
+	return width
+}
+
+func (r TerminalTableRenderer) minColumnWidth(column Column) int {
+	return 1
+}
+
+func (r TerminalTableRenderer) writeHead(w io.Writer, table *Table, maxLenOfCol []int) error {
+	chars := r.boxChars()
+	widths := r.fitColumnWidths(maxLenOfCol)
+
+	var head strings.Builder
+
+	if table.Caption != "" {
+		head.WriteString(sanitizeTerminalCell(strings.Join(strings.Fields(table.Caption), " ")) + "\n")
+	}
+
+	head.WriteString(constructBoxBorderLine(widths, chars.horizontal, chars.topLeft, chars.topMiddle, chars.topRight))
+
+	lines, err := r.constructRowLines(table, table.Header(), widths, 0, r.HeaderStyle)
+	if err != nil {
+		return err
+	}
+
+	head.WriteString(lines)
+	head.WriteString(constructBoxBorderLine(widths, chars.horizontal, chars.middleLeft, chars.middleMiddle, chars.middleRight))
+
+	_, err = io.WriteString(w, head.String())
+	return err
+}
+
+func (r TerminalTableRenderer) writeRow(w io.Writer, table *Table, row []string, maxLenOfCol []int, rowIdx int) error {
+	lines, err := r.constructRowLines(table, row, r.fitColumnWidths(maxLenOfCol), rowIdx, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, lines)
+	return err
+}
+
+func (r TerminalTableRenderer) writeTail(w io.Writer, table *Table, maxLenOfCol []int) error {
+	chars := r.boxChars()
+
+	_, err := io.WriteString(w, constructBoxBorderLine(r.fitColumnWidths(maxLenOfCol), chars.horizontal, chars.bottomLeft, chars.bottomMiddle, chars.bottomRight))
+	return err
+}
+
+// Get the characters of the box style
+func (r TerminalTableRenderer) boxChars() boxChars {
+	if r.Style == ASCIIBoxStyle {
+		return asciiBoxChars
+	}
+
+	return unicodeBoxChars
+}
+
+// Shrink the widest columns until the table fits in MaxWidth, or every column is a single character wide
+func (r TerminalTableRenderer) fitColumnWidths(maxLenOfCol []int) []int {
+	widths := make([]int, len(maxLenOfCol))
+	copy(widths, maxLenOfCol)
+
+	if r.MaxWidth <= 0 {
+		return widths
+	}
+
+	// every column takes up its width, a space on each side and its right border, plus the left border of the table
+	tableWidth := 1
+	for _, colLen := range widths {
+		tableWidth += colLen + 3
+	}
+
+	for ; tableWidth > r.MaxWidth; tableWidth-- {
+		widest := 0
+		for i, colLen := range widths {
+			if colLen > widths[widest] {
+				widest = i
+			}
+		}
+
+		if widths[widest] <= 1 {
+			break
+		}
+
+		widths[widest]--
+	}
+
+	return widths
+}
+
+// Construct the lines of a row, with the values wrapped or truncated to the width of their column.
+// The padded values are displayed with the ANSI style, if there is one.
+func (r TerminalTableRenderer) constructRowLines(table *Table, row []string, widths []int, currRowIdx int, style string) (string, error) {
+	chars := r.boxChars()
+
+	cells := make([][]string, len(row))
+	height := 1
+
+	for i, val := range row {
+		for _, line := range splitCellLines(sanitizeTerminalCell(val)) {
+			if r.Overflow == TruncateCells {
+				cells[i] = append(cells[i], truncateToWidth(line, widths[i], chars.ellipsis, r.WidthMode))
+			} else {
+				cells[i] = append(cells[i], wrapToWidth(line, widths[i], r.WidthMode)...)
+			}
+		}
+
+		height = max(height, len(cells[i]))
+	}
+
+	var rowLines strings.Builder
+
+	for lineIdx := range height {
+		rowLines.WriteString(chars.vertical)
+
+		for i, lines := range cells {
+			line := ""
+			if lineIdx < len(lines) {
+				line = lines[lineIdx]
+			}
+
+			// a single character can be wider than a shrunk column
+			padded, err := padAligned(line, max(widths[i], stringWidth(line, r.WidthMode)), table.Columns[i].Align, r.WidthMode)
+			if err != nil {
+				return "", paddingError(line, table.Columns[i], currRowIdx, i, err)
+			}
+
+			if style != "" {
+				padded = "\x1b[" + style + "m" + padded + "\x1b[0m"
+			}
+
+			rowLines.WriteString(" " + padded + " " + chars.vertical)
+		}
+
+		rowLines.WriteString("\n")
+	}
+
+	return rowLines.String(), nil
+}
+
+// Construct a horizontal border line of the table, e.g. ├──┼──┤
+func constructBoxBorderLine(widths []int, horizontal string, left string, middle string, right string) string {
+	borders := make([]string, len(widths))
+	for i, colLen := range widths {
+		// one space of padding on each side of the values
+		borders[i] = strings.Repeat(horizontal, colLen+2)
+	}
+
+	return left + strings.Join(borders, middle) + right + "\n"
+}
+
+// Split a line into lines no wider than the width, between words when possible
+func wrapToWidth(line string, width int, mode WidthMode) []string {
+	if stringWidth(line, mode) <= width {
+		return []string{line}
+	}
+
+	var lines []string
+	current := ""
+
+	for _, word := range strings.Fields(line) {
+		switch {
+		case current == "":
+			current = word
+		case stringWidth(current+" "+word, mode) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+
+		// words wider than the column are broken wherever they have to
+		for stringWidth(current, mode) > width {
+			head, rest := splitAtWidth(current, width, mode)
+			lines = append(lines, head)
+			current = rest
+		}
+	}
+
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+// Cut a line to the width, ending it with the ellipsis if anything was cut
+func truncateToWidth(line string, width int, ellipsis string, mode WidthMode) string {
+	if stringWidth(line, mode) <= width {
+		return line
+	}
+
+	if stringWidth(ellipsis, mode) >= width {
+		head, _ := splitAtWidth(line, width, mode)
+		return head
+	}
+
+	head, _ := splitAtWidth(line, width-stringWidth(ellipsis, mode), mode)
+	return head + ellipsis
+}
+
+// Split a string after as many runes as fit in the width. At least one rune is kept in the head.
+func splitAtWidth(val string, width int, mode WidthMode) (string, string) {
+	end := 0
+
+	for idx, r := range val {
+		if idx > 0 && stringWidth(val[:idx+len(string(r))], mode) > width {
+			break
+		}
+
+		end = idx + len(string(r))
+	}
+
+	return val[:end], val[end:]
+}
+
+// Replace the control characters of a value, which would be interpreted by the terminal.
+// Tabs become spaces, line breaks are kept.
+func sanitizeTerminalCell(val string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return unicode.ReplacementChar
+		}
+
+		return r
+	}, strings.ReplaceAll(val, "\r\n", "\n"))
+}
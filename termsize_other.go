@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package csv2mdtable
+
+import "os"
+
+// The size of the terminal cannot be queried on this platform, TerminalWidth falls back to $COLUMNS
+func terminalColumns(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package csv2mdtable
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Get the number of columns of the terminal the file is attached to, 0 if it is not a terminal
+func terminalColumns(f *os.File) int {
+	var size struct {
+		rows, cols, xPixels, yPixels uint16
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+
+	return int(size.cols)
+}